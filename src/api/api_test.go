@@ -0,0 +1,206 @@
+package api_test
+
+import (
+	"context"
+	"fl/api"
+	"fl/fakeflows"
+	"fl/fakeflows/fakeflowstest"
+	"strings"
+	"testing"
+)
+
+// test generation for a valid user
+func TestGenerateCommand(t *testing.T) {
+	server := fakeflowstest.Start(t)
+	server.AddUser(fakeflows.User{FLID: "test", Quota: -1})
+	server.SetCommand("list files", "ls -l")
+
+	res, err := api.GenerateCommand(context.Background(), "list files", "bash", "test")
+	if err != nil || !res.Valid || res.Quota || res.Cmd != "ls -l" {
+		t.Fatalf(`GenerateCommand("list files") = (%+v, %v), expected valid "ls -l"`, res, err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Input["language"] != "bash" {
+		t.Fatalf(`expected one generate request with language "bash", got %+v`, requests)
+	}
+}
+
+// test the environment context is sent only when there is one
+func TestGenerateCommandWithContext(t *testing.T) {
+	server := fakeflowstest.Start(t)
+	server.AddUser(fakeflows.User{FLID: "test", Quota: -1})
+
+	if _, err := api.GenerateCommandWithContext(context.Background(), "list files", "bash", "test", "OS: Linux\n"); err != nil {
+		t.Fatalf("GenerateCommandWithContext() failed: %v", err)
+	}
+	if _, err := api.GenerateCommand(context.Background(), "list files", "bash", "test"); err != nil {
+		t.Fatalf("GenerateCommand() failed: %v", err)
+	}
+
+	requests := server.Requests()
+	if requests[0].Input["context"] != "OS: Linux\n" {
+		t.Fatalf(`expected the context in the request, got %+v`, requests[0].Input)
+	}
+	if _, ok := requests[1].Input["context"]; ok {
+		t.Fatalf(`expected no context in the request, got %+v`, requests[1].Input)
+	}
+}
+
+// test several candidates are returned when requested
+func TestGenerateCandidates(t *testing.T) {
+	server := fakeflowstest.Start(t)
+	server.AddUser(fakeflows.User{FLID: "test", Quota: -1})
+	server.SetCommand("list files", "ls -l")
+
+	res, err := api.GenerateCandidates(context.Background(), "list files", "bash", "test", "", 3)
+	if err != nil || len(res.Candidates) != 3 || res.Cmd != "ls -l" {
+		t.Fatalf(`GenerateCandidates("list files", 3) = (%+v, %v), expected 3 candidates led by "ls -l"`, res, err)
+	}
+}
+
+// test an unknown FLID is reported as an invalid token
+func TestGenerateCommandInvalidToken(t *testing.T) {
+	fakeflowstest.Start(t)
+
+	res, err := api.GenerateCommand(context.Background(), "list files", "", "unknown")
+	if err != nil || res.Valid {
+		t.Fatalf(`GenerateCommand() = (%+v, %v), expected invalid token`, res, err)
+	}
+}
+
+// test the quota is reported once exhausted
+func TestGenerateCommandQuota(t *testing.T) {
+	server := fakeflowstest.Start(t)
+	server.AddUser(fakeflows.User{FLID: "test", Quota: 1})
+
+	res, err := api.GenerateCommand(context.Background(), "list files", "", "test")
+	if err != nil || res.Quota {
+		t.Fatalf(`first GenerateCommand() = (%+v, %v), expected quota available`, res, err)
+	}
+
+	res, err = api.GenerateCommand(context.Background(), "list files", "", "test")
+	if err != nil || !res.Quota {
+		t.Fatalf(`second GenerateCommand() = (%+v, %v), expected quota exhausted`, res, err)
+	}
+}
+
+// test scripted error responses are surfaced as errors
+func TestGenerateCommandError(t *testing.T) {
+	server := fakeflowstest.Start(t)
+	server.Script(fakeflows.Generate, fakeflows.Response{Status: 500, Raw: "flow failed"})
+
+	_, err := api.GenerateCommand(context.Background(), "list files", "", "test")
+	if err == nil {
+		t.Fatalf(`GenerateCommand() expected an error for a 500 response`)
+	}
+}
+
+// test the explanation of a pipeline
+func TestExplainCommand(t *testing.T) {
+	server := fakeflowstest.Start(t)
+	server.AddUser(fakeflows.User{FLID: "test", Quota: -1})
+	server.AddUser(fakeflows.User{FLID: "exhausted"})
+
+	res, err := api.ExplainCommand(context.Background(), "ls -l | wc -l", "bash", "test")
+	if err != nil || !res.Valid || len(res.Stages) != 2 || len(res.Stages[0].Flags) != 1 {
+		t.Fatalf(`ExplainCommand() = (%+v, %v), expected two stages`, res, err)
+	}
+
+	res, err = api.ExplainCommand(context.Background(), "ls -l", "bash", "exhausted")
+	if err != nil || !res.Valid || !res.Quota {
+		t.Fatalf(`ExplainCommand() with no quota left = (%+v, %v), expected the quota flag`, res, err)
+	}
+}
+
+// test the hosted deployment has no explain flow unless one is configured
+func TestExplainNotConfigured(t *testing.T) {
+	fakeflowstest.Start(t)
+	api.SetEndpoints(api.EndpointConfig{})
+
+	if _, err := api.ExplainCommand(context.Background(), "ls", "bash", "test"); err != api.ErrExplainNotConfigured || !strings.Contains(err.Error(), "api.paths.explain") {
+		t.Fatalf(`ExplainCommand() without an explain endpoint = %v, expected an error naming api.paths.explain`, err)
+	}
+}
+
+// test guest and GitHub logins
+func TestLogin(t *testing.T) {
+	server := fakeflowstest.Start(t)
+	server.AddGitHubToken("gh-token", "github-user")
+
+	flid, err := api.LoginGuestUserByIP(context.Background())
+	if err != nil || flid == "" || server.User(flid) == nil {
+		t.Fatalf(`LoginGuestUserByIP() = ("%s", %v), expected a registered guest`, flid, err)
+	}
+
+	flid, err = api.LoginCommand(context.Background(), "gh-token")
+	if err != nil || flid != "github-user" {
+		t.Fatalf(`LoginCommand() = ("%s", %v), expected "github-user"`, flid, err)
+	}
+}
+
+// test requests that only read are retried after a 503 and those that use
+// quota or change the subscription are not
+func TestRetries(t *testing.T) {
+	server := fakeflowstest.Start(t)
+	server.AddUser(fakeflows.User{FLID: "test", Quota: -1, Status: "paid"})
+	server.SetCommand("list files", "ls -l")
+
+	server.Script(fakeflows.Generate, fakeflows.Response{Status: 503, Raw: "unavailable"})
+	if res, err := api.GenerateCommand(context.Background(), "list files", "bash", "test"); err == nil {
+		t.Fatalf(`GenerateCommand() after a 503 = %+v, expected the failure without a retry`, res)
+	}
+
+	server.Script(fakeflows.StatusOfSubscription, fakeflows.Response{Status: 503, Raw: "unavailable"})
+	if _, err := api.StatusOfSubscription(context.Background(), "test"); err != nil {
+		t.Fatalf(`StatusOfSubscription() after a 503 failed: %v, expected a retry that succeeds`, err)
+	}
+
+	server.Script(fakeflows.CancelSubscription, fakeflows.Response{Status: 503, Raw: "unavailable"})
+	if _, err := api.CancelSubscription(context.Background(), "test"); err == nil {
+		t.Fatalf(`CancelSubscription() after a 503 succeeded, expected the failure without a retry`)
+	}
+	if status := server.User("test").Status; status != "paid" {
+		t.Fatalf(`status after a failed cancel = "%s", expected "paid"`, status)
+	}
+
+	sent := map[fakeflows.Endpoint]int{}
+	for _, r := range server.Requests() {
+		sent[r.Endpoint]++
+	}
+	if sent[fakeflows.Generate] != 1 || sent[fakeflows.StatusOfSubscription] != 2 || sent[fakeflows.CancelSubscription] != 1 {
+		t.Fatalf("requests sent = %v, expected 1 generation, 2 status checks and 1 cancel", sent)
+	}
+}
+
+// test the subscription life cycle
+func TestSubscription(t *testing.T) {
+	server := fakeflowstest.Start(t)
+	server.AddUser(fakeflows.User{FLID: "guest", Status: "guest"})
+	server.AddUser(fakeflows.User{FLID: "paid", Status: "paid"})
+
+	status, err := api.StartSubscription(context.Background(), "guest")
+	if err != nil || status.Status != "guest" || status.SubscriptionURL == "" {
+		t.Fatalf(`StartSubscription() = (%+v, %v), expected a subscription URL`, status, err)
+	}
+
+	_, err = api.CancelSubscription(context.Background(), "guest")
+	if err == nil {
+		t.Fatalf(`CancelSubscription() expected an error without a subscription`)
+	}
+
+	status, err = api.CancelSubscription(context.Background(), "paid")
+	if err != nil || status.Status != "canceling" {
+		t.Fatalf(`CancelSubscription() = (%+v, %v), expected "canceling"`, status, err)
+	}
+
+	status, err = api.StatusOfSubscription(context.Background(), "paid")
+	if err != nil || status.Status != "canceling" {
+		t.Fatalf(`StatusOfSubscription() = (%+v, %v), expected "canceling"`, status, err)
+	}
+
+	_, err = api.StatusOfSubscription(context.Background(), "unknown")
+	if err == nil {
+		t.Fatalf(`StatusOfSubscription() expected an error for an unknown flid`)
+	}
+}
@@ -1,12 +1,109 @@
 package api
 
+import (
+	"strings"
+)
+
 const (
 	GitHubClientID = "Ov23liak5XRTpeHgGDtx"
 
-	LoginGuestAPI           = "https://flow.pstmn-beta.io/api/54a53271f71447c8aadd14463ab9d0ef"
-	LoginGitHubAPI          = "https://flow.pstmn-beta.io/api/8bb625a9aa6f4996b48c9d10fb178c60"
-	GenerateCmdAPI          = "https://flow.pstmn-beta.io/api/0f14f0dc85cf4a269bf094c576a45143"
-	StartSubscriptionAPI    = "https://flow.pstmn-beta.io/api/53e444447b6a46b5acb1bee676fbc3da"
-	CancelSubscriptionAPI   = "https://flow.pstmn-beta.io/api/b5d6a27bde594a62b81a8d85c91b179f"
-	StatusOfSubscriptionAPI = "https://flow.pstmn-beta.io/api/3b53e2513fe54686832524d5b12180ce"
+	// the hosted Flows deployment
+	DefaultBaseURL = "https://flow.pstmn-beta.io"
+)
+
+// URLs of the flows on the hosted deployment; API calls use EndpointURL so
+// that a self-hosted deployment can be configured
+const (
+	LoginGuestAPI           = DefaultBaseURL + "/api/54a53271f71447c8aadd14463ab9d0ef"
+	LoginGitHubAPI          = DefaultBaseURL + "/api/8bb625a9aa6f4996b48c9d10fb178c60"
+	GenerateCmdAPI          = DefaultBaseURL + "/api/0f14f0dc85cf4a269bf094c576a45143"
+	StartSubscriptionAPI    = DefaultBaseURL + "/api/53e444447b6a46b5acb1bee676fbc3da"
+	CancelSubscriptionAPI   = DefaultBaseURL + "/api/b5d6a27bde594a62b81a8d85c91b179f"
+	StatusOfSubscriptionAPI = DefaultBaseURL + "/api/3b53e2513fe54686832524d5b12180ce"
 )
+
+// endpoint names, used as config keys (api.paths.<name>) and environment
+// variables (FL_API_<NAME>) to override the path of each endpoint
+const (
+	EndpointLoginGuest         = "login_guest"
+	EndpointLoginGitHub        = "login_github"
+	EndpointGenerate           = "generate"
+	EndpointExplain            = "explain"
+	EndpointStartSubscription  = "start_subscription"
+	EndpointCancelSubscription = "cancel_subscription"
+	EndpointStatusSubscription = "status_subscription"
+)
+
+var endpointNames = []string{
+	EndpointLoginGuest,
+	EndpointLoginGitHub,
+	EndpointGenerate,
+	EndpointExplain,
+	EndpointStartSubscription,
+	EndpointCancelSubscription,
+	EndpointStatusSubscription,
+}
+
+// the hosted deployment has no explain flow, explaining commands with the
+// Flows generator needs a self-hosted one set with api.paths.explain
+var defaultPaths = map[string]string{
+	EndpointLoginGuest:         strings.TrimPrefix(LoginGuestAPI, DefaultBaseURL),
+	EndpointLoginGitHub:        strings.TrimPrefix(LoginGitHubAPI, DefaultBaseURL),
+	EndpointGenerate:           strings.TrimPrefix(GenerateCmdAPI, DefaultBaseURL),
+	EndpointStartSubscription:  strings.TrimPrefix(StartSubscriptionAPI, DefaultBaseURL),
+	EndpointCancelSubscription: strings.TrimPrefix(CancelSubscriptionAPI, DefaultBaseURL),
+	EndpointStatusSubscription: strings.TrimPrefix(StatusOfSubscriptionAPI, DefaultBaseURL),
+}
+
+// location of the Flows endpoints, empty values use the defaults
+type EndpointConfig struct {
+	BaseURL string            // scheme and host of the Flows deployment
+	Paths   map[string]string // endpoint name -> path relative to BaseURL, or an absolute URL
+}
+
+var endpoints = EndpointConfig{BaseURL: DefaultBaseURL, Paths: defaultPaths}
+
+// names of all endpoints
+func EndpointNames() []string {
+	return endpointNames
+}
+
+// environment variable that overrides the path of an endpoint
+func EndpointEnv(name string) string {
+	return "FL_API_" + strings.ToUpper(name)
+}
+
+// default path of an endpoint on the hosted Flows deployment
+func DefaultPath(name string) string {
+	return defaultPaths[name]
+}
+
+// override the endpoints used by all API calls
+func SetEndpoints(conf EndpointConfig) {
+	endpoints = EndpointConfig{BaseURL: DefaultBaseURL, Paths: map[string]string{}}
+	if conf.BaseURL != "" {
+		endpoints.BaseURL = strings.TrimRight(conf.BaseURL, "/")
+	}
+
+	for _, name := range endpointNames {
+		endpoints.Paths[name] = defaultPaths[name]
+		if path := conf.Paths[name]; path != "" {
+			endpoints.Paths[name] = path
+		}
+	}
+}
+
+// the URL of an endpoint, empty if it has no path
+func EndpointURL(name string) string {
+	path := endpoints.Paths[name]
+	if path == "" {
+		return ""
+	}
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return endpoints.BaseURL + path
+}
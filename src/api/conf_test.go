@@ -0,0 +1,54 @@
+package api_test
+
+import (
+	"fl/api"
+	"testing"
+)
+
+// test endpoint paths are resolved against the base URL unless absolute
+func TestEndpointURL(t *testing.T) {
+	t.Cleanup(func() { api.SetEndpoints(api.EndpointConfig{}) })
+
+	api.SetEndpoints(api.EndpointConfig{})
+	if url := api.EndpointURL(api.EndpointGenerate); url != api.GenerateCmdAPI {
+		t.Fatalf(`EndpointURL(%q) = "%s", expected the hosted URL "%s"`, api.EndpointGenerate, url, api.GenerateCmdAPI)
+	}
+
+	api.SetEndpoints(api.EndpointConfig{
+		BaseURL: "http://flows.example.com:8080/",
+		Paths: map[string]string{
+			api.EndpointGenerate:   "/flows/generate",
+			api.EndpointExplain:    "flows/explain",
+			api.EndpointLoginGuest: "https://login.example.com/guest",
+		},
+	})
+	for name, expected := range map[string]string{
+		api.EndpointGenerate:           "http://flows.example.com:8080/flows/generate",
+		api.EndpointExplain:            "http://flows.example.com:8080/flows/explain",
+		api.EndpointLoginGuest:         "https://login.example.com/guest",
+		api.EndpointCancelSubscription: "http://flows.example.com:8080" + api.DefaultPath(api.EndpointCancelSubscription),
+	} {
+		if url := api.EndpointURL(name); url != expected {
+			t.Fatalf(`EndpointURL(%q) = "%s", expected "%s"`, name, url, expected)
+		}
+	}
+
+	// paths that are not set again go back to their defaults
+	api.SetEndpoints(api.EndpointConfig{BaseURL: "http://flows.example.com"})
+	if url := api.EndpointURL(api.EndpointGenerate); url != "http://flows.example.com"+api.DefaultPath(api.EndpointGenerate) {
+		t.Fatalf(`EndpointURL(%q) = "%s", expected the default path on the new base URL`, api.EndpointGenerate, url)
+	}
+}
+
+// test every endpoint but explain has a default path, and the environment
+// variable of an endpoint
+func TestEndpointNames(t *testing.T) {
+	for _, name := range api.EndpointNames() {
+		if api.DefaultPath(name) == "" && name != api.EndpointExplain {
+			t.Fatalf(`DefaultPath(%q) is empty`, name)
+		}
+	}
+	if env := api.EndpointEnv(api.EndpointStatusSubscription); env != "FL_API_STATUS_SUBSCRIPTION" {
+		t.Fatalf(`EndpointEnv(%q) = "%s", expected FL_API_STATUS_SUBSCRIPTION`, api.EndpointStatusSubscription, env)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"fl/utils"
+	"net/http"
+	"time"
+)
+
+const endpointCheckTimeout = 5 * time.Second
+
+// result of checking that an endpoint is reachable
+type EndpointStatus struct {
+	Name       string
+	URL        string
+	StatusCode int // HTTP status of the response, 0 if unreachable
+	Latency    time.Duration
+	Err        error
+}
+
+// any HTTP response means the endpoint is reachable, Flows rejects GET requests
+func (s EndpointStatus) Reachable() bool {
+	return s.Err == nil && s.StatusCode < 500
+}
+
+// check that every endpoint responds, without calling the flows; endpoints
+// without a path are skipped
+func CheckEndpoints(ctx context.Context) []EndpointStatus {
+	statuses := []EndpointStatus{}
+	for _, name := range endpointNames {
+		status := EndpointStatus{Name: name, URL: EndpointURL(name)}
+		if status.URL == "" {
+			continue
+		}
+
+		start := time.Now()
+		resp, err := get(ctx, status.URL)
+		status.Latency = time.Since(start)
+		if err != nil {
+			status.Err = err
+		} else {
+			status.StatusCode = resp.StatusCode
+			resp.Body.Close()
+		}
+
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// a GET with the shared client, without retries so failures show at once
+func get(ctx context.Context, url string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, endpointCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return utils.HTTPClient().Do(req)
+}
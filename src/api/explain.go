@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fl/utils"
+	"fmt"
+)
+
+type apiExplainCommandInput struct {
+	Input struct {
+		Cmd      string `json:"cmd"`
+		Language string `json:"language"`
+		FLID     string `json:"flid"`
+	} `json:"Input"`
+}
+
+type apiExplainCommandOutput struct {
+	Output ExplainResult `json:"Output"`
+}
+
+// a single flag or option used by a pipeline stage
+type ExplainedFlag struct {
+	Flag        string `json:"flag"`
+	Description string `json:"description"`
+}
+
+// one stage of a pipeline (e.g., each side of a '|', '&&' or ';')
+type ExplainedStage struct {
+	Command     string          `json:"command"`
+	Description string          `json:"description"`
+	Flags       []ExplainedFlag `json:"flags"`
+}
+
+type ExplainResult struct {
+	Valid   bool             `json:"valid"`
+	Quota   bool             `json:"quota"`
+	Summary string           `json:"summary"`
+	Stages  []ExplainedStage `json:"stages"`
+}
+
+// the explain endpoint has no default path, the hosted deployment has no explain flow
+var ErrExplainNotConfigured = fmt.Errorf("the hosted Flows deployment cannot explain commands, set api.paths.explain or %s to a self-hosted explain flow, or use the openai or local generator", EndpointEnv(EndpointExplain))
+
+func ExplainCommand(ctx context.Context, cmd string, language string, flid string) (*ExplainResult, error) {
+	body := apiExplainCommandInput{}
+	body.Input.Cmd = cmd
+	body.Input.Language = language
+	body.Input.FLID = flid
+
+	url := EndpointURL(EndpointExplain)
+	if url == "" {
+		return nil, ErrExplainNotConfigured
+	}
+
+	statusCode, response, err := utils.PostJSON(utils.Idempotent(ctx), url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != 200 {
+		err = fmt.Errorf("failed to explain command: %s", string(response))
+		return nil, err
+	}
+
+	res := apiExplainCommandOutput{}
+	err = json.Unmarshal(response, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Output, nil
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fl/utils"
 	"fmt"
@@ -8,9 +9,11 @@ import (
 
 type apiGenerateCommandInput struct {
 	Input struct {
-		Prompt   string `json:"prompt"`
-		Language string `json:"language"`
-		FLID     string `json:"flid"`
+		Prompt     string `json:"prompt"`
+		Language   string `json:"language"`
+		FLID       string `json:"flid"`
+		Context    string `json:"context,omitempty"`
+		Candidates int    `json:"candidates,omitempty"`
 	} `json:"Input"`
 }
 
@@ -19,18 +22,48 @@ type apiGenerateCommandOutput struct {
 }
 
 type GeneratedCommandResult struct {
-	Valid bool   `json:"valid"`
-	Quota bool   `json:"quota"`
-	Cmd   string `json:"cmd"`
+	Valid      bool        `json:"valid"`
+	Quota      bool        `json:"quota"`
+	Cmd        string      `json:"cmd"`                  // the preferred command
+	Candidates []Candidate `json:"candidates,omitempty"` // alternatives when more than one was requested
+	Cached     bool        `json:"-"`                    // answered from the local cache
 }
 
-func GenerateCommand(prompt string, language string, flid string) (*GeneratedCommandResult, error) {
+// an alternative command, e.g. pure coreutils vs jq
+type Candidate struct {
+	Cmd         string `json:"cmd"`
+	Description string `json:"description"`
+}
+
+// make Cmd the first candidate when only candidates were returned
+func (res *GeneratedCommandResult) normalize() {
+	if res.Cmd == "" && len(res.Candidates) > 0 {
+		res.Cmd = res.Candidates[0].Cmd
+	}
+}
+
+func GenerateCommand(ctx context.Context, prompt string, language string, flid string) (*GeneratedCommandResult, error) {
+	return GenerateCommandWithContext(ctx, prompt, language, flid, "")
+}
+
+// generate a command for the environment described by envContext, e.g. the OS and installed tools
+func GenerateCommandWithContext(ctx context.Context, prompt string, language string, flid string, envContext string) (*GeneratedCommandResult, error) {
+	return GenerateCandidates(ctx, prompt, language, flid, envContext, 1)
+}
+
+// generate up to n alternative commands, the result lists them as candidates when n > 1
+func GenerateCandidates(ctx context.Context, prompt string, language string, flid string, envContext string, n int) (*GeneratedCommandResult, error) {
 	body := apiGenerateCommandInput{}
 	body.Input.Prompt = prompt
 	body.Input.Language = language
 	body.Input.FLID = flid
+	body.Input.Context = envContext
+	if n > 1 {
+		body.Input.Candidates = n
+	}
 
-	statusCode, response, err := utils.PostJSON(GenerateCmdAPI, body)
+	// every generation uses quota, so it is only retried when it never reached the server
+	statusCode, response, err := utils.PostJSON(ctx, EndpointURL(EndpointGenerate), body)
 	if err != nil {
 		return nil, err
 	}
@@ -46,5 +79,6 @@ func GenerateCommand(prompt string, language string, flid string) (*GeneratedCom
 		return nil, err
 	}
 
+	res.Output.normalize()
 	return &res.Output, nil
 }
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	BackendFlows  = "flows"  // Postman Flows (default)
+	BackendOpenAI = "openai" // OpenAI-compatible chat completions
+	BackendLocal  = "local"  // llama.cpp or Ollama style completions
+
+	defaultOpenAIURL   = "https://api.openai.com/v1/chat/completions"
+	defaultOpenAIModel = "gpt-4o-mini"
+	defaultLocalURL    = "http://localhost:11434/api/generate"
+	defaultLocalModel  = "llama3"
+	defaultMaxTokens   = 512
+)
+
+// a request to generate a command from a natural language prompt
+type GenerateRequest struct {
+	Prompt     string
+	Language   string
+	FLID       string
+	History    []Turn // earlier turns of a chat session, oldest first
+	Context    string // the user's environment, empty unless opted in
+	Data       string // delimited untrusted data the prompt refers to, e.g. piped stdin
+	Candidates int    // number of alternative commands wanted, 0 or 1 for a single command
+}
+
+// a prompt and the command that was generated for it
+type Turn struct {
+	Prompt string
+	Cmd    string
+}
+
+// the prompt followed by the data it refers to
+func (req GenerateRequest) DataPrompt() string {
+	if req.Data == "" {
+		return req.Prompt
+	}
+	return req.Prompt + "\n\n" + req.Data
+}
+
+// the prompt with the earlier turns folded in, for backends that take a single prompt
+func (req GenerateRequest) ContextPrompt() string {
+	if len(req.History) == 0 {
+		return req.DataPrompt()
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Earlier in this conversation:\n")
+	for _, t := range req.History {
+		fmt.Fprintf(&sb, "Request: %s\nCommand: %s\n", t.Prompt, t.Cmd)
+	}
+	fmt.Fprintf(&sb, "\nChange the last command according to this request: %s", req.DataPrompt())
+	return sb.String()
+}
+
+// a backend that turns prompts into commands and explains them
+type Generator interface {
+	Name() string
+	Generate(ctx context.Context, req GenerateRequest) (*GeneratedCommandResult, error)
+	Explain(ctx context.Context, cmd string, language string, flid string) (*ExplainResult, error)
+}
+
+// generation settings read from the config file
+type GeneratorConfig struct {
+	Backend     string  // one of the Backend* constants, defaults to flows
+	Model       string  // model name for openai and local backends
+	URL         string  // endpoint for openai and local backends
+	APIKey      string  // bearer token for openai backends, defaults to $OPENAI_API_KEY
+	Temperature float64 // sampling temperature
+	MaxTokens   int     // upper bound on generated tokens
+}
+
+// only the Flows backend needs an FLID, self-hosted backends do not
+func (conf GeneratorConfig) RequiresLogin() bool {
+	backend := strings.ToLower(conf.Backend)
+	return backend == "" || backend == BackendFlows
+}
+
+// names of all supported backends
+func Backends() []string {
+	return []string{BackendFlows, BackendOpenAI, BackendLocal}
+}
+
+func NewGenerator(conf GeneratorConfig) (Generator, error) {
+	if conf.MaxTokens <= 0 {
+		conf.MaxTokens = defaultMaxTokens
+	}
+
+	switch strings.ToLower(conf.Backend) {
+	case "", BackendFlows:
+		return FlowsGenerator{}, nil
+
+	case BackendOpenAI:
+		if conf.URL == "" {
+			conf.URL = defaultOpenAIURL
+		}
+		if conf.Model == "" {
+			conf.Model = defaultOpenAIModel
+		}
+		if conf.APIKey == "" {
+			conf.APIKey = os.Getenv("OPENAI_API_KEY")
+		}
+		return OpenAIGenerator{conf}, nil
+
+	case BackendLocal:
+		if conf.URL == "" {
+			conf.URL = defaultLocalURL
+		}
+		if conf.Model == "" {
+			conf.Model = defaultLocalModel
+		}
+		return LocalGenerator{conf}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported backend '%s' (supported: %s)", conf.Backend, strings.Join(Backends(), ", "))
+}
+
+// the Postman Flows backend
+type FlowsGenerator struct{}
+
+func (FlowsGenerator) Name() string {
+	return BackendFlows
+}
+
+func (FlowsGenerator) Generate(ctx context.Context, req GenerateRequest) (*GeneratedCommandResult, error) {
+	return GenerateCandidates(ctx, req.ContextPrompt(), req.Language, req.FLID, req.Context, req.Candidates)
+}
+
+func (FlowsGenerator) Explain(ctx context.Context, cmd string, language string, flid string) (*ExplainResult, error) {
+	return ExplainCommand(ctx, cmd, language, flid)
+}
+
+// instructions for models that are not behind the Flows backend
+func generateInstructions(language string, envContext string) string {
+	if language == "" {
+		language = "bash"
+	}
+	instructions := fmt.Sprintf("You convert natural language descriptions of command line tasks into a single valid %s command. "+
+		"Reply with the command only: no explanation, no markdown and no code fences.", language)
+
+	if envContext != "" {
+		instructions += "\n\nThe command will run in this environment, only use flags and tools that it supports:\n" + envContext
+	}
+	return instructions
+}
+
+// instructions for the alternatives of a command, in place of generateInstructions
+func candidatesInstructions(language string, envContext string, n int) string {
+	if language == "" {
+		language = "bash"
+	}
+	instructions := fmt.Sprintf("You convert natural language descriptions of command line tasks into %d alternative valid %s commands, "+
+		"for example one using only standard tools, one using a more specialized tool, or a one-liner and a short script. Reply with JSON only, in the form "+
+		`{"candidates": [{"cmd": string, "description": string}]}`+
+		" with the best command first and a one sentence description of how each differs.", n, language)
+
+	if envContext != "" {
+		instructions += "\n\nThe commands will run in this environment, only use flags and tools that it supports:\n" + envContext
+	}
+	return instructions
+}
+
+// the instructions and parser for a request, depending on how many commands it wants
+func (req GenerateRequest) instructions() string {
+	if req.Candidates > 1 {
+		return candidatesInstructions(req.Language, req.Context, req.Candidates)
+	}
+	return generateInstructions(req.Language, req.Context)
+}
+
+func (req GenerateRequest) parse(text string) *GeneratedCommandResult {
+	res := GeneratedCommandResult{Valid: true}
+	if req.Candidates > 1 {
+		text = cleanCompletion(text)
+		if err := json.Unmarshal([]byte(text), &res); err == nil && len(res.Candidates) > 0 {
+			if len(res.Candidates) > req.Candidates {
+				res.Candidates = res.Candidates[:req.Candidates]
+			}
+			res.Valid = true
+			res.normalize()
+			return &res
+		}
+		res = GeneratedCommandResult{Valid: true}
+	}
+
+	// a single command, or candidates that could not be parsed
+	res.Cmd = cleanCompletion(text)
+	return &res
+}
+
+func explainInstructions(language string) string {
+	if language == "" {
+		language = "bash"
+	}
+	return fmt.Sprintf("You explain %s commands. Reply with JSON only, in the form "+
+		`{"summary": string, "stages": [{"command": string, "description": string, "flags": [{"flag": string, "description": string}]}]}`+
+		" with one stage per pipeline stage and one entry for every flag used.", language)
+}
+
+var codeFence = regexp.MustCompile("(?s)^```[a-zA-Z]*\\n?(.*?)\\n?```$")
+
+// strip markdown code fences and whitespace that models tend to add
+func cleanCompletion(text string) string {
+	text = strings.TrimSpace(text)
+	if m := codeFence.FindStringSubmatch(text); m != nil {
+		text = strings.TrimSpace(m[1])
+	}
+	return strings.Trim(text, "`")
+}
+
+// parse a JSON explanation, falling back to a plain text summary
+func parseExplanation(text string) *ExplainResult {
+	res := ExplainResult{Valid: true}
+	text = cleanCompletion(text)
+	if err := json.Unmarshal([]byte(text), &res); err != nil {
+		res.Summary = text
+		res.Stages = nil
+	}
+	res.Valid = true
+	return &res
+}
@@ -0,0 +1,245 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fl/api"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// a request received by a fake completion backend
+type backendRequest struct {
+	Authorization string
+	Body          map[string]interface{}
+}
+
+// start a completion backend that answers every request with status and
+// reply, and records the requests
+func startBackend(t *testing.T, status int, reply string) (string, *[]backendRequest) {
+	t.Helper()
+
+	requests := []backendRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := backendRequest{Authorization: r.Header.Get("Authorization")}
+		if err := json.NewDecoder(r.Body).Decode(&req.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/api/generate", &requests
+}
+
+func newGenerator(t *testing.T, conf api.GeneratorConfig) api.Generator {
+	t.Helper()
+	gen, err := api.NewGenerator(conf)
+	if err != nil {
+		t.Fatalf("NewGenerator(%+v) failed: %v", conf, err)
+	}
+	return gen
+}
+
+// test the chat completion request and the command taken from the reply
+func TestOpenAIGenerate(t *testing.T) {
+	url, requests := startBackend(t, http.StatusOK, `{"choices": [{"message": {"role": "assistant", "content": "`+"```bash\\nls -l\\n```"+`"}}]}`)
+	gen := newGenerator(t, api.GeneratorConfig{Backend: "openai", URL: url, Model: "gpt-4o", APIKey: "sk-test", Temperature: 0.2})
+
+	req := api.GenerateRequest{Prompt: "list files", Language: "zsh", History: []api.Turn{{Prompt: "list", Cmd: "ls"}}}
+	res, err := gen.Generate(context.Background(), req)
+	if err != nil || !res.Valid || res.Cmd != "ls -l" {
+		t.Fatalf(`Generate("list files") = (%+v, %v), expected valid "ls -l" without the code fence`, res, err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(*requests))
+	}
+	r := (*requests)[0]
+	if r.Authorization != "Bearer sk-test" {
+		t.Fatalf(`Authorization = "%s", expected "Bearer sk-test"`, r.Authorization)
+	}
+	if r.Body["model"] != "gpt-4o" || r.Body["temperature"] != 0.2 || r.Body["max_tokens"] != float64(512) {
+		t.Fatalf("request = %+v, expected model gpt-4o, temperature 0.2 and the default 512 max tokens", r.Body)
+	}
+
+	// the system instructions, then the earlier turn, then the prompt
+	messages, _ := r.Body["messages"].([]interface{})
+	roles := []string{}
+	for _, m := range messages {
+		roles = append(roles, m.(map[string]interface{})["role"].(string))
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,user" {
+		t.Fatalf("message roles = %v, expected system, user, assistant, user", roles)
+	}
+	system := messages[0].(map[string]interface{})["content"].(string)
+	last := messages[3].(map[string]interface{})["content"].(string)
+	if !strings.Contains(system, "valid zsh command") || last != "list files" {
+		t.Fatalf(`messages = %+v, expected zsh instructions and the prompt last`, messages)
+	}
+}
+
+// test the model and API key defaults of the openai backend
+func TestOpenAIDefaults(t *testing.T) {
+	url, requests := startBackend(t, http.StatusOK, `{"choices": [{"message": {"content": "ls"}}]}`)
+
+	t.Setenv("OPENAI_API_KEY", "sk-env")
+	gen := newGenerator(t, api.GeneratorConfig{Backend: "openai", URL: url})
+	if _, err := gen.Generate(context.Background(), api.GenerateRequest{Prompt: "list files"}); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	t.Setenv("OPENAI_API_KEY", "")
+	gen = newGenerator(t, api.GeneratorConfig{Backend: "openai", URL: url})
+	if _, err := gen.Generate(context.Background(), api.GenerateRequest{Prompt: "list files"}); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	r := *requests
+	if r[0].Body["model"] != "gpt-4o-mini" {
+		t.Fatalf(`model = %v, expected the default gpt-4o-mini`, r[0].Body["model"])
+	}
+	if r[0].Authorization != "Bearer sk-env" {
+		t.Fatalf(`Authorization = "%s", expected the key from $OPENAI_API_KEY`, r[0].Authorization)
+	}
+	if r[1].Authorization != "" {
+		t.Fatalf(`Authorization = "%s", expected none without a key`, r[1].Authorization)
+	}
+}
+
+// test errors of the openai backend are reported with their message
+func TestOpenAIErrors(t *testing.T) {
+	for _, test := range []struct {
+		status   int
+		reply    string
+		expected string
+	}{
+		{http.StatusUnauthorized, `{"error": {"message": "Incorrect API key provided"}}`, "Incorrect API key provided"},
+		{http.StatusBadRequest, `bad request`, "bad request"},
+		{http.StatusOK, `{"choices": []}`, "empty response"},
+	} {
+		url, _ := startBackend(t, test.status, test.reply)
+		gen := newGenerator(t, api.GeneratorConfig{Backend: "openai", URL: url, APIKey: "sk-test"})
+
+		res, err := gen.Generate(context.Background(), api.GenerateRequest{Prompt: "list files"})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Fatalf(`Generate() with reply %d "%s" = (%+v, %v), expected an error containing "%s"`, test.status, test.reply, res, err, test.expected)
+		}
+	}
+}
+
+// test explanations are parsed from JSON, or kept as a plain summary
+func TestOpenAIExplain(t *testing.T) {
+	url, requests := startBackend(t, http.StatusOK, `{"choices": [{"message": {"content": "{\"summary\": \"Counts files.\", \"stages\": [{\"command\": \"ls\"}, {\"command\": \"wc -l\", \"flags\": [{\"flag\": \"-l\"}]}]}"}}]}`)
+	gen := newGenerator(t, api.GeneratorConfig{Backend: "openai", URL: url})
+
+	res, err := gen.Explain(context.Background(), "ls | wc -l", "bash", "")
+	if err != nil || !res.Valid || res.Summary != "Counts files." || len(res.Stages) != 2 || len(res.Stages[1].Flags) != 1 {
+		t.Fatalf(`Explain("ls | wc -l") = (%+v, %v), expected two stages`, res, err)
+	}
+	messages, _ := (*requests)[0].Body["messages"].([]interface{})
+	if len(messages) != 2 || messages[1].(map[string]interface{})["content"] != "ls | wc -l" {
+		t.Fatalf("messages = %+v, expected the instructions and the command", messages)
+	}
+
+	url, _ = startBackend(t, http.StatusOK, `{"choices": [{"message": {"content": "Lists files."}}]}`)
+	gen = newGenerator(t, api.GeneratorConfig{Backend: "openai", URL: url})
+	res, err = gen.Explain(context.Background(), "ls", "bash", "")
+	if err != nil || !res.Valid || res.Summary != "Lists files." || len(res.Stages) != 0 {
+		t.Fatalf(`Explain("ls") = (%+v, %v), expected the plain text as the summary`, res, err)
+	}
+}
+
+// test the local completion request carries both Ollama and llama.cpp
+// parameters, and either reply is understood
+func TestLocalGenerate(t *testing.T) {
+	for _, reply := range []string{`{"response": "ls -l", "done": true}`, `{"content": "ls -l"}`} {
+		url, requests := startBackend(t, http.StatusOK, reply)
+		gen := newGenerator(t, api.GeneratorConfig{Backend: "local", URL: url, Temperature: 0.5, MaxTokens: 64})
+
+		req := api.GenerateRequest{Prompt: "list files", History: []api.Turn{{Prompt: "list", Cmd: "ls"}}}
+		res, err := gen.Generate(context.Background(), req)
+		if err != nil || !res.Valid || res.Cmd != "ls -l" {
+			t.Fatalf(`Generate("list files") with reply %s = (%+v, %v), expected valid "ls -l"`, reply, res, err)
+		}
+
+		body := (*requests)[0].Body
+		options, _ := body["options"].(map[string]interface{})
+		if body["model"] != "llama3" || body["stream"] != false || body["n_predict"] != float64(64) || body["temperature"] != 0.5 ||
+			options["num_predict"] != float64(64) || options["temperature"] != 0.5 {
+			t.Fatalf("request = %+v, expected the default model llama3 and the limits in both forms", body)
+		}
+
+		// the earlier turns are folded into the prompt, the instructions are
+		// sent once as the system prompt
+		prompt, _ := body["prompt"].(string)
+		system, _ := body["system"].(string)
+		if system == "" || strings.Contains(prompt, system) || !strings.Contains(prompt, "Request: list\nCommand: ls") || !strings.HasSuffix(prompt, "list files") {
+			t.Fatalf(`request = %+v, expected the instructions once and the earlier turn and the prompt`, body)
+		}
+	}
+}
+
+// test llama.cpp, which has no system prompt, gets the instructions in the prompt
+func TestLocalCompletion(t *testing.T) {
+	url, requests := startBackend(t, http.StatusOK, `{"content": "ls -l"}`)
+	url = strings.TrimSuffix(url, "/api/generate") + "/completion"
+	gen := newGenerator(t, api.GeneratorConfig{Backend: "local", URL: url})
+
+	if res, err := gen.Generate(context.Background(), api.GenerateRequest{Prompt: "list files"}); err != nil || res.Cmd != "ls -l" {
+		t.Fatalf(`Generate("list files") = (%+v, %v), expected "ls -l"`, res, err)
+	}
+
+	body := (*requests)[0].Body
+	prompt, _ := body["prompt"].(string)
+	if _, ok := body["system"]; ok || !strings.Contains(prompt, "\n\n") || !strings.HasSuffix(prompt, "list files") {
+		t.Fatalf(`request = %+v, expected the instructions before the prompt and no system field`, body)
+	}
+}
+
+// test errors of the local backend are reported with their message
+func TestLocalErrors(t *testing.T) {
+	for _, test := range []struct {
+		status   int
+		reply    string
+		expected string
+	}{
+		{http.StatusNotFound, `{"error": "model 'llama3' not found"}`, "model 'llama3' not found"},
+		{http.StatusBadRequest, `bad request`, "bad request"},
+	} {
+		url, _ := startBackend(t, test.status, test.reply)
+		gen := newGenerator(t, api.GeneratorConfig{Backend: "local", URL: url})
+
+		res, err := gen.Generate(context.Background(), api.GenerateRequest{Prompt: "list files"})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Fatalf(`Generate() with reply %d "%s" = (%+v, %v), expected an error containing "%s"`, test.status, test.reply, res, err, test.expected)
+		}
+	}
+}
+
+// test explanations from the local backend
+func TestLocalExplain(t *testing.T) {
+	url, requests := startBackend(t, http.StatusOK, `{"response": "{\"summary\": \"Lists files.\", \"stages\": [{\"command\": \"ls -l\"}]}"}`)
+	gen := newGenerator(t, api.GeneratorConfig{Backend: "local", URL: url, Model: "qwen2.5-coder"})
+
+	res, err := gen.Explain(context.Background(), "ls -l", "bash", "")
+	if err != nil || !res.Valid || res.Summary != "Lists files." || len(res.Stages) != 1 {
+		t.Fatalf(`Explain("ls -l") = (%+v, %v), expected one stage`, res, err)
+	}
+	if body := (*requests)[0].Body; body["model"] != "qwen2.5-coder" || !strings.HasSuffix(body["prompt"].(string), "ls -l") {
+		t.Fatalf("request = %+v, expected the configured model and the command", body)
+	}
+}
+
+// test unknown backends are rejected
+func TestUnsupportedBackend(t *testing.T) {
+	if _, err := api.NewGenerator(api.GeneratorConfig{Backend: "unknown"}); err == nil || !strings.Contains(err.Error(), "flows, openai, local") {
+		t.Fatalf(`NewGenerator("unknown") = %v, expected an error listing the backends`, err)
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fl/utils"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// the request carries both Ollama (/api/generate) and llama.cpp (/completion) parameters
+type localCompletionInput struct {
+	Model       string  `json:"model"`
+	System      string  `json:"system,omitempty"`
+	Prompt      string  `json:"prompt"`
+	Stream      bool    `json:"stream"`
+	Temperature float64 `json:"temperature"`
+	NPredict    int     `json:"n_predict"`
+	Options     struct {
+		Temperature float64 `json:"temperature"`
+		NumPredict  int     `json:"num_predict"`
+	} `json:"options"`
+}
+
+type localCompletionOutput struct {
+	Response string `json:"response"` // Ollama
+	Content  string `json:"content"`  // llama.cpp
+	Error    string `json:"error"`
+}
+
+// a self-hosted llama.cpp or Ollama style backend
+type LocalGenerator struct {
+	conf GeneratorConfig
+}
+
+func (LocalGenerator) Name() string {
+	return BackendLocal
+}
+
+func (g LocalGenerator) Generate(ctx context.Context, req GenerateRequest) (*GeneratedCommandResult, error) {
+	text, err := g.complete(ctx, req.instructions(), req.ContextPrompt())
+	if err != nil {
+		return nil, err
+	}
+
+	return req.parse(text), nil
+}
+
+func (g LocalGenerator) Explain(ctx context.Context, cmd string, language string, flid string) (*ExplainResult, error) {
+	text, err := g.complete(ctx, explainInstructions(language), cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseExplanation(text), nil
+}
+
+func (g LocalGenerator) complete(ctx context.Context, system string, prompt string) (string, error) {
+	body := localCompletionInput{
+		Model:       g.conf.Model,
+		System:      system,
+		Prompt:      prompt,
+		Temperature: g.conf.Temperature,
+		NPredict:    g.conf.MaxTokens,
+	}
+
+	// llama.cpp has no separate system prompt, the instructions lead the prompt
+	if u, err := url.Parse(g.conf.URL); err == nil && strings.HasSuffix(strings.TrimRight(u.Path, "/"), "/completion") {
+		body.System, body.Prompt = "", system+"\n\n"+prompt
+	}
+	body.Options.Temperature = g.conf.Temperature
+	body.Options.NumPredict = g.conf.MaxTokens
+
+	// completions change nothing on the server, so a failed one can be repeated
+	statusCode, response, err := utils.PostJSON(utils.Idempotent(ctx), g.conf.URL, body)
+	if err != nil {
+		return "", err
+	}
+
+	res := localCompletionOutput{}
+	err = json.Unmarshal(response, &res)
+	if statusCode != 200 || err != nil {
+		if res.Error != "" {
+			return "", fmt.Errorf("failed to generate command: %s", res.Error)
+		}
+		return "", fmt.Errorf("failed to generate command: %s", string(response))
+	}
+
+	if res.Response != "" {
+		return res.Response, nil
+	}
+	return res.Content, nil
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fl/utils"
 	"fmt"
@@ -20,11 +21,11 @@ type LoginResult struct {
 	FLID string `json:"flid"`
 }
 
-func LoginCommand(token string) (string, error) {
+func LoginCommand(ctx context.Context, token string) (string, error) {
 	body := apiLoginInput{}
 	body.Input.Token = token
 
-	statusCode, response, err := utils.PostJSON(LoginGitHubAPI, body)
+	statusCode, response, err := utils.PostJSON(ctx, EndpointURL(EndpointLoginGitHub), body)
 	if err != nil {
 		return "", err
 	}
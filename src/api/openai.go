@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fl/utils"
+	"fmt"
+)
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatInput struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Temperature float64         `json:"temperature"`
+	MaxTokens   int             `json:"max_tokens"`
+}
+
+type openAIChatOutput struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// an OpenAI-compatible chat completions backend
+type OpenAIGenerator struct {
+	conf GeneratorConfig
+}
+
+func (OpenAIGenerator) Name() string {
+	return BackendOpenAI
+}
+
+func (g OpenAIGenerator) Generate(ctx context.Context, req GenerateRequest) (*GeneratedCommandResult, error) {
+	// earlier turns are replayed as the conversation so far
+	messages := []openAIMessage{}
+	for _, t := range req.History {
+		messages = append(messages, openAIMessage{Role: "user", Content: t.Prompt}, openAIMessage{Role: "assistant", Content: t.Cmd})
+	}
+	messages = append(messages, openAIMessage{Role: "user", Content: req.DataPrompt()})
+
+	text, err := g.complete(ctx, req.instructions(), messages...)
+	if err != nil {
+		return nil, err
+	}
+
+	return req.parse(text), nil
+}
+
+func (g OpenAIGenerator) Explain(ctx context.Context, cmd string, language string, flid string) (*ExplainResult, error) {
+	text, err := g.complete(ctx, explainInstructions(language), openAIMessage{Role: "user", Content: cmd})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseExplanation(text), nil
+}
+
+func (g OpenAIGenerator) complete(ctx context.Context, system string, messages ...openAIMessage) (string, error) {
+	body := openAIChatInput{
+		Model:       g.conf.Model,
+		Messages:    append([]openAIMessage{{Role: "system", Content: system}}, messages...),
+		Temperature: g.conf.Temperature,
+		MaxTokens:   g.conf.MaxTokens,
+	}
+
+	headers := map[string]string{}
+	if g.conf.APIKey != "" {
+		headers["Authorization"] = "Bearer " + g.conf.APIKey
+	}
+
+	// completions change nothing on the server, so a failed one can be repeated
+	statusCode, response, err := utils.PostJSONWithHeaders(utils.Idempotent(ctx), g.conf.URL, body, headers)
+	if err != nil {
+		return "", err
+	}
+
+	res := openAIChatOutput{}
+	err = json.Unmarshal(response, &res)
+	if statusCode != 200 || err != nil {
+		if res.Error != nil {
+			return "", fmt.Errorf("failed to generate command: %s", res.Error.Message)
+		}
+		return "", fmt.Errorf("failed to generate command: %s", string(response))
+	}
+
+	if len(res.Choices) == 0 {
+		return "", fmt.Errorf("failed to generate command: empty response")
+	}
+
+	return res.Choices[0].Message.Content, nil
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fl/utils"
 )
@@ -19,8 +20,8 @@ type apiRegisterOutput struct {
  * Register a user by their IP and return their FLID.
  * If the registration fails, an empty string or error is returned.
  */
-func LoginGuestUserByIP() (flid string, err error) {
-	ip, err := utils.GetExternalIP()
+func LoginGuestUserByIP(ctx context.Context) (flid string, err error) {
+	ip, err := utils.GetExternalIP(ctx)
 	if ip == "" || err != nil {
 		return
 	}
@@ -28,7 +29,7 @@ func LoginGuestUserByIP() (flid string, err error) {
 	input := apiRegisterInput{}
 	input.Input.IP = ip
 
-	_, response, err := utils.PostJSON(LoginGuestAPI, input)
+	_, response, err := utils.PostJSON(ctx, EndpointURL(EndpointLoginGuest), input)
 	if err != nil {
 		return
 	}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fl/utils"
 	"fmt"
@@ -25,11 +26,11 @@ type apiSubscriptionOutput struct {
 	Output SubscriptionResult `json:"Output"`
 }
 
-func StartSubscription(flid string) (*SubscriptionResult, error) {
+func StartSubscription(ctx context.Context, flid string) (*SubscriptionResult, error) {
 	body := apiSubscriptionInput{}
 	body.Input.FLID = flid
 
-	statusCode, response, err := utils.PostJSON(StartSubscriptionAPI, body)
+	statusCode, response, err := utils.PostJSON(ctx, EndpointURL(EndpointStartSubscription), body)
 	if err != nil {
 		return nil, err
 	}
@@ -53,11 +54,12 @@ func StartSubscription(flid string) (*SubscriptionResult, error) {
 	return &res.Output, nil
 }
 
-func CancelSubscription(flid string) (*SubscriptionResult, error) {
+func CancelSubscription(ctx context.Context, flid string) (*SubscriptionResult, error) {
 	body := apiSubscriptionInput{}
 	body.Input.FLID = flid
 
-	statusCode, response, err := utils.PostJSON(CancelSubscriptionAPI, body)
+	// canceling changes the subscription, so it is not repeated after a failure
+	statusCode, response, err := utils.PostJSON(ctx, EndpointURL(EndpointCancelSubscription), body)
 	if err != nil {
 		return nil, err
 	}
@@ -81,11 +83,12 @@ func CancelSubscription(flid string) (*SubscriptionResult, error) {
 	return &res.Output, nil
 }
 
-func StatusOfSubscription(flid string) (*SubscriptionResult, error) {
+func StatusOfSubscription(ctx context.Context, flid string) (*SubscriptionResult, error) {
 	body := apiSubscriptionInput{}
 	body.Input.FLID = flid
 
-	statusCode, response, err := utils.PostJSON(StatusOfSubscriptionAPI, body)
+	// the status only reads the subscription, so a failed check can be repeated
+	statusCode, response, err := utils.PostJSON(utils.Idempotent(ctx), EndpointURL(EndpointStatusSubscription), body)
 	if err != nil {
 		return nil, err
 	}
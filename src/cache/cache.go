@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fl/api"
+	"fl/utils"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTTL      = 7 * 24 * time.Hour
+	DefaultMaxBytes = 10 * 1024 * 1024
+
+	statsFile = "stats.json"
+	lockFile  = ".lock"
+)
+
+// entries expire after TTL and the oldest are evicted once the cache exceeds MaxBytes
+type Limits struct {
+	TTL      time.Duration // zero uses DefaultTTL
+	MaxBytes int64         // zero uses DefaultMaxBytes
+}
+
+// a content-addressed cache of generated commands, one file per entry
+type Cache struct {
+	dir    string
+	limits Limits
+}
+
+type entry struct {
+	Prompt   string                     `json:"prompt"`
+	Language string                     `json:"language"`
+	Backend  string                     `json:"backend"`
+	Created  time.Time                  `json:"created"`
+	Result   api.GeneratedCommandResult `json:"result"`
+}
+
+type Stats struct {
+	Entries int   `json:"-"`
+	Bytes   int64 `json:"-"`
+	Hits    int   `json:"hits"`
+	Misses  int   `json:"misses"`
+}
+
+func Open(dir string, limits Limits) *Cache {
+	if limits.TTL == 0 {
+		limits.TTL = DefaultTTL
+	}
+	if limits.MaxBytes == 0 {
+		limits.MaxBytes = DefaultMaxBytes
+	}
+	return &Cache{dir: dir, limits: limits}
+}
+
+// prompts that differ only in whitespace or trailing punctuation share an entry,
+// case is kept since it matters in file names and arguments
+func normalize(prompt string) string {
+	prompt = strings.Join(strings.Fields(prompt), " ")
+	return strings.TrimRight(prompt, ".?! ")
+}
+
+func key(prompt string, language string, backend string) string {
+	sum := sha256.Sum256([]byte(normalize(prompt) + "\x00" + language + "\x00" + backend))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(k string) string {
+	return filepath.Join(c.dir, k[:2], k+".json")
+}
+
+// the cached result for a prompt, nil if there is no fresh entry
+func (c *Cache) Get(prompt string, language string, backend string) *api.GeneratedCommandResult {
+	path := c.path(key(prompt, language, backend))
+
+	e := entry{}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &e)
+	}
+
+	if err != nil || time.Since(e.Created) > c.limits.TTL {
+		c.count(false)
+		return nil
+	}
+
+	// the modification time tracks use, so eviction removes the least recently used
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	c.count(true)
+	return &e.Result
+}
+
+// cache a result, only valid results with quota remaining are worth keeping
+func (c *Cache) Put(prompt string, language string, backend string, res *api.GeneratedCommandResult) error {
+	if !res.Valid || res.Quota || res.Cmd == "" {
+		return nil
+	}
+
+	e := entry{Prompt: prompt, Language: language, Backend: backend, Created: time.Now(), Result: *res}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	path := c.path(key(prompt, language, backend))
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err = utils.WriteFileAtomic(path, data, 0600); err != nil {
+		return err
+	}
+
+	return c.evict()
+}
+
+type file struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (c *Cache) files() ([]file, error) {
+	files := []file{}
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return filepath.SkipDir
+		} else if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".json" || d.Name() == statsFile {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, file{path, info.Size(), info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+// remove expired entries and the least recently used until the cache fits
+func (c *Cache) evict() error {
+	files, err := c.files()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	total := int64(0)
+	for _, f := range files {
+		total += f.size
+		if total > c.limits.MaxBytes || time.Since(f.modTime) > c.limits.TTL {
+			os.Remove(f.path)
+		}
+	}
+	return nil
+}
+
+func (c *Cache) Stats() (Stats, error) {
+	stats := c.readStats()
+
+	files, err := c.files()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, f := range files {
+		stats.Entries++
+		stats.Bytes += f.size
+	}
+	return stats, nil
+}
+
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.dir)
+}
+
+func (c *Cache) readStats() Stats {
+	stats := Stats{}
+	data, err := os.ReadFile(filepath.Join(c.dir, statsFile))
+	if err == nil {
+		json.Unmarshal(data, &stats)
+	}
+	return stats
+}
+
+// count a hit or miss, statistics are best effort
+func (c *Cache) count(hit bool) {
+	if os.MkdirAll(c.dir, 0700) != nil {
+		return
+	}
+
+	unlock, err := utils.LockFile(filepath.Join(c.dir, lockFile))
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	stats := c.readStats()
+	if hit {
+		stats.Hits++
+	} else {
+		stats.Misses++
+	}
+
+	if data, err := json.Marshal(stats); err == nil {
+		utils.WriteFileAtomic(filepath.Join(c.dir, statsFile), data, 0600)
+	}
+}
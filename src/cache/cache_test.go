@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"fl/api"
+	"testing"
+	"time"
+)
+
+// test equivalent prompts share an entry and prompts in another case or for
+// other backends do not
+func TestGetPut(t *testing.T) {
+	c := Open(t.TempDir(), Limits{})
+	res := &api.GeneratedCommandResult{Valid: true, Cmd: "ls -a"}
+
+	if c.Get("list all files", "bash", "flows") != nil {
+		t.Fatalf(`Get() on an empty cache expected nil`)
+	}
+
+	c.Put("list all files", "bash", "flows", res)
+
+	if got := c.Get("  list all   files? ", "bash", "flows"); got == nil || got.Cmd != "ls -a" {
+		t.Fatalf(`Get() of a normalized prompt = %+v, expected "ls -a"`, got)
+	}
+	if c.Get("List ALL files", "bash", "flows") != nil {
+		t.Fatalf(`Get() of a prompt in another case expected nil`)
+	}
+	if c.Get("list all files", "zsh", "flows") != nil {
+		t.Fatalf(`Get() for another langtool expected nil`)
+	}
+	if c.Get("list all files", "bash", "openai") != nil {
+		t.Fatalf(`Get() for another backend expected nil`)
+	}
+
+	stats, _ := c.Stats()
+	if stats.Entries != 1 || stats.Hits != 1 || stats.Misses != 4 {
+		t.Fatalf(`Stats() = %+v, expected 1 entry, 1 hit and 4 misses`, stats)
+	}
+}
+
+// test invalid results and exhausted quota are not cached
+func TestPutSkipsInvalid(t *testing.T) {
+	c := Open(t.TempDir(), Limits{})
+	c.Put("a", "", "flows", &api.GeneratedCommandResult{Valid: false, Cmd: "ls"})
+	c.Put("b", "", "flows", &api.GeneratedCommandResult{Valid: true, Quota: true, Cmd: "ls"})
+
+	if c.Get("a", "", "flows") != nil || c.Get("b", "", "flows") != nil {
+		t.Fatalf(`expected invalid and quota limited results to not be cached`)
+	}
+}
+
+// test entries expire
+func TestTTL(t *testing.T) {
+	c := Open(t.TempDir(), Limits{TTL: time.Millisecond})
+	c.Put("list all files", "", "flows", &api.GeneratedCommandResult{Valid: true, Cmd: "ls -a"})
+	time.Sleep(5 * time.Millisecond)
+
+	if c.Get("list all files", "", "flows") != nil {
+		t.Fatalf(`Get() of an expired entry expected nil`)
+	}
+}
+
+// test the least recently used entries are evicted when the cache is full
+func TestEviction(t *testing.T) {
+	c := Open(t.TempDir(), Limits{MaxBytes: 400})
+
+	prompts := []string{"one", "two", "three", "four", "five"}
+	for _, p := range prompts {
+		c.Put(p, "", "flows", &api.GeneratedCommandResult{Valid: true, Cmd: "echo " + p})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stats, _ := c.Stats()
+	if stats.Bytes > 400 || stats.Entries == 0 {
+		t.Fatalf(`Stats() = %+v, expected at most 400 bytes`, stats)
+	}
+	if c.Get("five", "", "flows") == nil {
+		t.Fatalf(`expected the most recent entry to be kept`)
+	}
+	if c.Get("one", "", "flows") != nil {
+		t.Fatalf(`expected the oldest entry to be evicted`)
+	}
+}
+
+// test prompts that differ only in the case of file names miss the cache
+func TestCaseSensitive(t *testing.T) {
+	c := Open(t.TempDir(), Limits{})
+	c.Put("rename foo.txt to bar.txt", "bash", "flows", &api.GeneratedCommandResult{Valid: true, Cmd: "mv foo.txt bar.txt"})
+
+	if got := c.Get("rename Foo.txt to Bar.txt", "bash", "flows"); got != nil {
+		t.Fatalf(`Get("rename Foo.txt to Bar.txt") = %+v, expected nil`, got)
+	}
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"fl/api"
+	"fmt"
+)
+
+// a Generator that answers repeated prompts from the cache
+type Generator struct {
+	api.Generator
+	cache   *Cache
+	backend string // identifies the backend and model in cache keys
+	refresh bool   // skip cached results but still cache new ones
+}
+
+func Wrap(gen api.Generator, cache *Cache, backend string, refresh bool) Generator {
+	return Generator{Generator: gen, cache: cache, backend: backend, refresh: refresh}
+}
+
+func (g Generator) Generate(ctx context.Context, req api.GenerateRequest) (*api.GeneratedCommandResult, error) {
+	// refinements depend on the conversation and data prompts on the data, not just the prompt
+	if len(req.History) > 0 || req.Data != "" {
+		return g.Generator.Generate(ctx, req)
+	}
+
+	// commands generated for an environment are only reused in the same environment
+	backend := g.backend
+	if req.Context != "" {
+		backend += "\x00" + req.Context
+	}
+	if req.Candidates > 1 {
+		backend += fmt.Sprintf("\x00candidates=%d", req.Candidates)
+	}
+
+	if !g.refresh {
+		if res := g.cache.Get(req.Prompt, req.Language, backend); res != nil {
+			res.Cached = true
+			return res, nil
+		}
+	}
+
+	res, err := g.Generator.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	g.cache.Put(req.Prompt, req.Language, backend, res)
+	return res, nil
+}
@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"context"
+	"fl/api"
+	"fl/utils"
+	"fmt"
+)
+
+// only the most recent turns are sent back as context
+const MaxContextTurns = 10
+
+// a generated command and the prompt that produced it
+type Version struct {
+	Prompt string
+	Cmd    string
+}
+
+// a conversation that refines a command, every generated command is kept as a version
+type Session struct {
+	gen      api.Generator
+	language string
+	flid     string
+	Context  string // the user's environment, sent with every prompt
+	Versions []Version
+}
+
+func NewSession(gen api.Generator, language string, flid string) *Session {
+	return &Session{gen: gen, language: language, flid: flid}
+}
+
+// generate the next version, the earlier versions are sent as context so the
+// prompt can be a follow-up such as "make it recursive"
+func (s *Session) Refine(ctx context.Context, prompt string) (*api.GeneratedCommandResult, error) {
+	req := api.GenerateRequest{Prompt: prompt, Language: s.language, FLID: s.flid, Context: s.Context}
+
+	turns := s.Versions
+	if len(turns) > MaxContextTurns {
+		turns = turns[len(turns)-MaxContextTurns:]
+	}
+	for _, v := range turns {
+		req.History = append(req.History, api.Turn{Prompt: v.Prompt, Cmd: v.Cmd})
+	}
+
+	res, err := s.gen.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Valid && res.Cmd != "" {
+		s.Versions = append(s.Versions, Version{Prompt: prompt, Cmd: res.Cmd})
+	}
+	return res, nil
+}
+
+// the n-th version counting from 1, 0 is the latest
+func (s *Session) Version(n int) (*Version, error) {
+	if len(s.Versions) == 0 {
+		return nil, fmt.Errorf("no command has been generated yet")
+	}
+	if n == 0 {
+		n = len(s.Versions)
+	}
+	if n < 1 || n > len(s.Versions) {
+		return nil, fmt.Errorf("there is no version %d (1-%d)", n, len(s.Versions))
+	}
+	return &s.Versions[n-1], nil
+}
+
+// unified diff from version a to version b
+func (s *Session) Diff(a int, b int) (string, error) {
+	if len(s.Versions) < 2 {
+		return "", fmt.Errorf("nothing to compare yet")
+	}
+	from, err := s.Version(a)
+	if err != nil {
+		return "", err
+	}
+	to, err := s.Version(b)
+	if err != nil {
+		return "", err
+	}
+	return utils.UnifiedDiff(fmt.Sprintf("v%d", a), fmt.Sprintf("v%d", b), from.Cmd+"\n", to.Cmd+"\n"), nil
+}
+
+// forget the conversation and start over
+func (s *Session) Reset() {
+	s.Versions = nil
+}
@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"context"
+	"fl/api"
+	"strings"
+	"testing"
+)
+
+// a generator that returns scripted commands and records the requests
+type stubGenerator struct {
+	cmds     []string
+	requests []api.GenerateRequest
+}
+
+func (g *stubGenerator) Name() string {
+	return "stub"
+}
+
+func (g *stubGenerator) Generate(ctx context.Context, req api.GenerateRequest) (*api.GeneratedCommandResult, error) {
+	g.requests = append(g.requests, req)
+	cmd := g.cmds[0]
+	g.cmds = g.cmds[1:]
+	return &api.GeneratedCommandResult{Valid: true, Cmd: cmd}, nil
+}
+
+func (g *stubGenerator) Explain(ctx context.Context, cmd string, language string, flid string) (*api.ExplainResult, error) {
+	return &api.ExplainResult{Valid: true}, nil
+}
+
+// test follow-up prompts send the earlier versions as context
+func TestRefine(t *testing.T) {
+	gen := &stubGenerator{cmds: []string{"find . -name '*.go'", "find . -name '*.go' -size +1M"}}
+	session := NewSession(gen, "bash", "test")
+
+	if _, err := session.Refine(context.Background(), "find go files"); err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	if _, err := session.Diff(0, 1); err == nil || err.Error() != "nothing to compare yet" {
+		t.Fatalf(`Diff(0, 1) with one version = %v, expected "nothing to compare yet"`, err)
+	}
+	if _, err := session.Refine(context.Background(), "only large ones"); err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+
+	if len(gen.requests[0].History) != 0 {
+		t.Fatalf("first request has history %+v, expected none", gen.requests[0].History)
+	}
+	history := gen.requests[1].History
+	if len(history) != 1 || history[0].Prompt != "find go files" || history[0].Cmd != "find . -name '*.go'" {
+		t.Fatalf("second request has history %+v, expected the first version", history)
+	}
+
+	v, err := session.Version(0)
+	if err != nil || v.Cmd != "find . -name '*.go' -size +1M" {
+		t.Fatalf(`Version(0) = (%+v, %v), expected the latest version`, v, err)
+	}
+	if _, err := session.Version(3); err == nil {
+		t.Fatalf(`Version(3) succeeded with only two versions`)
+	}
+
+	diff, err := session.Diff(1, 2)
+	if err != nil || !strings.Contains(diff, "-find . -name '*.go'\n") || !strings.Contains(diff, "+find . -name '*.go' -size +1M\n") {
+		t.Fatalf("Diff(1, 2) = (%q, %v), expected the changed line", diff, err)
+	}
+
+	session.Reset()
+	if len(session.Versions) != 0 {
+		t.Fatalf("Reset() kept %d versions", len(session.Versions))
+	}
+}
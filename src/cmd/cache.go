@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fl/cache"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func addCacheCommand(rootCmd *cobra.Command, flags *FlagConfig) {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the response cache",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	statsCmd := &cobra.Command{
+		Use:           "stats",
+		Short:         "Show cache size and hit rate",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stats, err := cache.Open(flags.CacheDir, flags.CacheLimits).Stats()
+			if err != nil {
+				return err
+			}
+
+			hitRate := 0.0
+			if lookups := stats.Hits + stats.Misses; lookups > 0 {
+				hitRate = 100 * float64(stats.Hits) / float64(lookups)
+			}
+
+			fmt.Println("location:", flags.CacheDir)
+			fmt.Println("entries:", stats.Entries)
+			fmt.Printf("size: %.1f KB\n", float64(stats.Bytes)/1024)
+			fmt.Printf("hits: %d, misses: %d (%.0f%% hit rate)\n", stats.Hits, stats.Misses, hitRate)
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	clearCmd := &cobra.Command{
+		Use:           "clear",
+		Short:         "Delete all cached commands",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cache.Open(flags.CacheDir, flags.CacheLimits).Clear()
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	cacheCmd.AddCommand(statsCmd)
+	cacheCmd.AddCommand(clearCmd)
+
+	rootCmd.AddCommand(cacheCmd)
+}
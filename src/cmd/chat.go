@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fl/chat"
+	"fl/exec"
+	"fl/history"
+	"fl/langtool"
+	"fl/safety"
+	"fl/utils"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const chatHelp = `Type a request to generate a command, then follow-ups such as "make it recursive" to refine it.
+  /list              list the versions generated so far
+  /diff [a] [b]      show what changed between two versions (default: the last two)
+  /copy [n]          copy a version to the clipboard (default: the latest)
+  /run [n]           execute a version
+  /save <file> [n]   write a version to a file
+  /reset             start over without the earlier versions as context
+  /help              show this help
+  /quit              end the session`
+
+// an interactive session reading prompts and commands from stdin
+type chatREPL struct {
+	ctx     context.Context
+	session *chat.Session
+	lt      *langtool.Langtool
+	flags   *FlagConfig
+	in      *bufio.Reader
+}
+
+func addChatCommand(rootCmd *cobra.Command, flags *FlagConfig) {
+	chatCmd := &cobra.Command{
+		Use:           "chat [prompt]",
+		Short:         "Refine a command interactively over several prompts",
+		Args:          cobra.ArbitraryArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.GeneratorConf.RequiresLogin() {
+				LoadFLID(flags)
+			}
+			if flags.FLID == "" && flags.GeneratorConf.RequiresLogin() {
+				return LoginMessage(true)
+			}
+
+			lt, err := langtool.Lookup(flags.Langtool)
+			if err != nil {
+				return err
+			}
+
+			gen, err := NewGenerator(flags)
+			if err != nil {
+				return err
+			}
+
+			session := chat.NewSession(gen, lt.Name, flags.FLID)
+			session.Context = EnvironmentContext(flags)
+
+			repl := chatREPL{
+				ctx:     cmd.Context(),
+				session: session,
+				lt:      lt,
+				flags:   flags,
+				in:      bufio.NewReader(os.Stdin),
+			}
+			repl.run(strings.Join(args, " "))
+			return cmd.Context().Err()
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	rootCmd.AddCommand(chatCmd)
+}
+
+func (r *chatREPL) run(prompt string) {
+	fmt.Println(strings.SplitN(chatHelp, "\n", 2)[0] + " Type /help for commands.")
+
+	for {
+		if prompt == "" {
+			fmt.Print("> ")
+			line, err := r.in.ReadString('\n')
+			if err != nil && line == "" {
+				fmt.Println()
+				return
+			}
+			prompt = strings.TrimSpace(line)
+		}
+
+		if prompt == "" {
+			continue
+		}
+
+		var done bool
+		if strings.HasPrefix(prompt, "/") {
+			done = r.command(strings.Fields(prompt))
+		} else {
+			done = r.refine(prompt)
+		}
+		if done || r.ctx.Err() != nil {
+			return
+		}
+		prompt = ""
+	}
+}
+
+// generate the next version, returns true if the session cannot continue
+func (r *chatREPL) refine(prompt string) bool {
+	start := time.Now()
+	res, err := r.session.Refine(r.ctx, prompt)
+	if err != nil {
+		fmt.Printf("Error generating a command: %v\n", err)
+		return false
+	}
+
+	// invalid token, no command
+	if !res.Valid {
+		fmt.Println("Your access code is invalid.")
+		LoginMessage(true)
+		return true
+	}
+
+	if res.Cmd == "" {
+		fmt.Println("No command was generated, try rephrasing the request.")
+		return false
+	}
+
+	n := len(r.session.Versions)
+	fmt.Printf("[%d] %s\n", n, res.Cmd)
+	r.record(history.Entry{Prompt: prompt, Langtool: r.lt.Name, Cmd: res.Cmd, Duration: time.Since(start)})
+
+	if err := r.lt.Validate(res.Cmd); err != nil {
+		fmt.Printf("Warning: the generated command may not be valid %s: %v\n", r.lt.Name, err)
+	}
+
+	if n > 1 {
+		diff, _ := r.session.Diff(n-1, n)
+		fmt.Print(diff)
+	}
+
+	if res.Quota {
+		fmt.Println(`
+Warning: You have exhausted your allowed quota.
+Features will be limited and your access may get cut off entirely.
+Use 'fl subscription login --subscribe' to subscribe and continue using the tool.`)
+		return true
+	}
+	return false
+}
+
+// handle a /command, returns true to end the session
+func (r *chatREPL) command(args []string) bool {
+	var err error
+	switch args[0] {
+	case "/quit", "/exit", "/q":
+		return true
+
+	case "/help", "/?":
+		fmt.Println(chatHelp)
+
+	case "/list", "/ls":
+		for i, v := range r.session.Versions {
+			fmt.Printf("[%d] %s\n    # %s\n", i+1, v.Cmd, v.Prompt)
+		}
+
+	case "/diff":
+		err = r.diff(args[1:])
+
+	case "/copy":
+		var v *chat.Version
+		if v, err = r.version(args[1:]); err == nil {
+			if err = utils.Clip(v.Cmd); err == nil {
+				fmt.Println("Copied to the clipboard.")
+			}
+		}
+
+	case "/run":
+		var v *chat.Version
+		if v, err = r.version(args[1:]); err == nil {
+			err = r.execute(v)
+		}
+
+	case "/save":
+		if len(args) < 2 {
+			err = fmt.Errorf("usage: /save <file> [n]")
+			break
+		}
+		var v *chat.Version
+		if v, err = r.version(args[2:]); err == nil {
+			if err = utils.WriteFileAtomic(args[1], []byte(v.Cmd), 0755); err == nil {
+				fmt.Printf("Saved to %s.\n", args[1])
+			}
+		}
+
+	case "/reset":
+		r.session.Reset()
+		fmt.Println("Started over.")
+
+	default:
+		err = fmt.Errorf("unknown command %s, type /help for commands", args[0])
+	}
+
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+	return false
+}
+
+// the version named by the first argument, e.g. 2 or v2, or the latest
+func (r *chatREPL) version(args []string) (*chat.Version, error) {
+	n, err := versionNumber(args, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return r.session.Version(n)
+}
+
+func (r *chatREPL) diff(args []string) error {
+	latest := len(r.session.Versions)
+	a, err := versionNumber(args, 0, latest-1)
+	if err != nil {
+		return err
+	}
+	b, err := versionNumber(args, 1, latest)
+	if err != nil {
+		return err
+	}
+
+	diff, err := r.session.Diff(a, b)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Printf("Versions %d and %d are the same.\n", a, b)
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+func versionNumber(args []string, i int, fallback int) (int, error) {
+	if len(args) <= i {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(args[i], "v"))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid version '%s'", args[i])
+	}
+	return n, nil
+}
+
+// run a version with the same checks as a regular run
+func (r *chatREPL) execute(v *chat.Version) error {
+	if !r.lt.Executable() {
+		return fmt.Errorf("%s commands cannot be executed", r.lt.Name)
+	}
+	if err := r.lt.Validate(v.Cmd); err != nil {
+		return fmt.Errorf("the command is not valid %s: %v", r.lt.Name, err)
+	}
+
+	// destructive commands need a typed confirmation
+	if report := safety.Analyze(v.Cmd, r.lt.Name); report.Dangerous() {
+		fmt.Printf("%s\nWould you like to execute the command? Type '%s' to continue: ", report, safety.ConfirmPhrase)
+		line, _ := r.in.ReadString('\n')
+		if strings.TrimSpace(line) != safety.ConfirmPhrase {
+			return nil
+		}
+	} else if len(report.Notices) > 0 {
+		fmt.Print(report)
+	}
+
+	start := time.Now()
+	code, stderr, err := exec.CommandWithShell(r.lt.Shell, v.Cmd).StreamCapture(r.ctx)
+	r.record(history.Entry{Prompt: v.Prompt, Langtool: r.lt.Name, Cmd: v.Cmd, Executed: true, ExitCode: code, Stderr: stderr, Duration: time.Since(start)})
+
+	if err != nil {
+		return fmt.Errorf("error while executing command: %s", err)
+	}
+	if code != 0 {
+		fmt.Printf("The command exited with status %d.\n", code)
+	}
+	return nil
+}
+
+func (r *chatREPL) record(e history.Entry) {
+	if _, err := history.Open(r.flags.HistoryFile, r.flags.HistoryRetention).Add(e); err != nil {
+		utils.Log(r.flags.Verbose, "Error saving history: %v\n", err)
+	}
+}
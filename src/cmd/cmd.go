@@ -1,6 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"fl/api"
+	"fl/cache"
+	"fl/config"
+	"fl/environment"
+	"fl/history"
+	"fl/input"
+	"fl/langtool"
+	"fl/profile"
+	"fl/redact"
+	"fl/utils"
+	"fmt"
 	"os"
 	"strings"
 
@@ -8,20 +20,45 @@ import (
 )
 
 type FlagConfig struct {
-	Verbose                bool   // verbose output while running
-	Explain                bool   // explainer command
-	PromptRun, AutoExecute bool   // prompt to run generated commands or auto run
-	Outfile                string // write generated command to file
-	Langtool               string // generate command for specific shell or a tool
-	Prompt                 string // command prompt
+	Verbose                bool            // verbose output while running
+	Explain                bool            // explainer command
+	PromptRun, AutoExecute bool            // prompt to run generated commands or auto run
+	Sandbox                bool            // preview the command in a scratch copy of the working directory
+	Outfile                string          // write generated command to file
+	Langtool               string          // generate command for specific shell or a tool
+	Prompt                 string          // command prompt
+	NoCache                bool            // do not answer from the response cache
+	ShowContext            bool            // preview the environment context instead of generating
+	Candidates             int             // number of alternative commands to choose from
+	Widget                 bool            // called by a shell widget, only the command is written to stdout
+	Output                 string          // text, plain or json, text on a terminal and plain otherwise
+	Files                  []string        // files sent with the prompt as data
+	Sources                []*input.Source // data set by subcommands, e.g. the stderr for fl fix
+	ShowRedactions         bool            // show what was redacted from the prompt
+	Profile                string          // the configuration profile in use
+	Offline                bool            // suggest commands from the offline corpus instead of generating
 
-	// these are properties from config file
-	AutoExecuteConf bool
-	LangtoolConf    string
-	FLID            string
+	// these are properties from the profile's config file
+	AutoExecuteConf     bool
+	LangtoolConf        string
+	FLID                string              // read from the credential store
+	CredentialStoreConf string              // where the FLID is kept: auto, keyring or file
+	GeneratorConf       api.GeneratorConfig // generation backend, model and parameters
+	EndpointsConf       api.EndpointConfig  // location of a self-hosted Flows deployment
+	HTTPConf            utils.HTTPConfig    // timeouts, retries, proxy and TLS of API requests
+	ContextConf         environment.Toggles // environment context categories sent with prompts
+	RedactConf          redact.Config       // secrets redacted from prompts before they are sent
+
+	Profiles         *profile.Profiles // named configurations and their location
+	ConfigFile       *config.File      // the profile's config file
+	Settings         *config.Settings  // every config key and where its value comes from
+	HistoryFile      string            // generation history store
+	HistoryRetention history.Retention // size and age limits of the history
+	CacheDir         string            // response cache location
+	CacheLimits      cache.Limits      // expiry and size limits of the response cache
 }
 
-func ParseCommandLine(args []string, filepath string, flags *FlagConfig) error {
+func ParseCommandLine(ctx context.Context, args []string, flags *FlagConfig) error {
 	rootCmd := &cobra.Command{
 		Use:   "fl <prompt>",
 		Short: "A command-line tool for generating command line scripts using AI",
@@ -36,21 +73,172 @@ func ParseCommandLine(args []string, filepath string, flags *FlagConfig) error {
 
 	rootCmd.PersistentFlags().BoolVarP(&flags.PromptRun, "prompt", "p", false, "Prompt to run generated commands")
 	rootCmd.PersistentFlags().BoolVarP(&flags.AutoExecute, "run", "r", flags.AutoExecuteConf, "Automatically execute generated commands (suppresses prompt)")
-	//TODO//rootCmd.PersistentFlags().BoolVarP(&flags.Explain, "explain", "e", false, "Explain the generated command")
+	rootCmd.PersistentFlags().BoolVar(&flags.Sandbox, "sandbox", false, "Run generated commands in a copy of the working directory first and show what changed")
+	rootCmd.PersistentFlags().BoolVarP(&flags.Explain, "explain", "e", false, "Explain the generated command")
 
+	rootCmd.PersistentFlags().BoolVar(&flags.NoCache, "no-cache", false, "Do not reuse a cached command for the same prompt")
+	rootCmd.Flags().BoolVar(&flags.Widget, "widget", false, "Only write the generated command to stdout (used by fl init)")
+	rootCmd.Flags().MarkHidden("widget")
+	rootCmd.Flags().IntVarP(&flags.Candidates, "candidates", "n", 1, "Generate several alternative commands to choose from")
+	rootCmd.Flags().BoolVar(&flags.Offline, "offline", false, "Suggest commands from the offline corpus without contacting the API (see fl lookup)")
+	rootCmd.Flags().StringArrayVarP(&flags.Files, "file", "f", nil, "Send a file with the prompt as data for the command to process (repeatable)")
+	rootCmd.PersistentFlags().BoolVar(&flags.ShowRedactions, "show-redactions", false, "Show the prompt as it was sent and the secrets redacted from it")
+	rootCmd.PersistentFlags().BoolVar(&flags.ShowContext, "show-context", false, "Show the environment context that is sent with prompts")
+	rootCmd.PersistentFlags().StringVar(&flags.Output, "output", "", "Output format: text, plain (only the command on stdout) or json (default: text on a terminal, plain otherwise)")
 	rootCmd.PersistentFlags().StringVarP(&flags.Outfile, "outfile", "o", "", "Write generated command to file")
-	//TODO//rootCmd.PersistentFlags().StringVarP(&flags.Langtool, "langtool", "l", flags.LangtoolConf, "Generate command for specific shell or a tool")
+	rootCmd.PersistentFlags().StringVar(&flags.Profile, "profile", flags.Profile, "Use a named configuration profile (default: $FL_PROFILE or the profile chosen with fl profile use)")
+	rootCmd.PersistentFlags().StringVarP(&flags.Langtool, "langtool", "l", flags.LangtoolConf, "Generate command for specific shell or a tool (see fl langtools)")
 
 	// subscribe commands
-	addSubscribeCommand(rootCmd, filepath, flags)
+	addSubscribeCommand(rootCmd, flags)
 
 	// config commands
-	addConfCommand(rootCmd, filepath, flags)
+	addConfCommand(rootCmd, flags)
+
+	// configuration profiles
+	addProfileCommand(rootCmd, flags)
+
+	// list supported shells and tools
+	addLangtoolCommand(rootCmd)
+
+	// generation history
+	addHistoryCommand(rootCmd, flags)
+
+	// response cache
+	addCacheCommand(rootCmd, flags)
+
+	// interactive refinement
+	addChatCommand(rootCmd, flags)
+
+	// shell integration
+	addInitCommand(rootCmd)
+
+	// correct failed commands
+	addFixCommand(rootCmd, flags)
+
+	// offline cheat sheets
+	addLookupCommand(rootCmd, flags)
+
+	// development tools
+	addDevCommand(rootCmd)
 
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 	exitAfterHelp(rootCmd, 0)
 	rootCmd.SetArgs(args)
-	return rootCmd.Execute()
+	err := rootCmd.ExecuteContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// normalize aliases so the backend always receives the canonical name
+	if flags.Langtool != "" {
+		lt, err := langtool.Lookup(flags.Langtool)
+		if err != nil {
+			return err
+		}
+		flags.Langtool = lt.Name
+	}
+
+	return nil
+}
+
+// the configured generation backend, answering repeated prompts from the
+// response cache and redacting secrets from everything that is sent
+func NewGenerator(flags *FlagConfig) (api.Generator, error) {
+	backend, err := api.NewGenerator(flags.GeneratorConf)
+	if err != nil {
+		return nil, err
+	}
+
+	redactor, err := redact.New(flags.RedactConf)
+	if err != nil {
+		return nil, err
+	}
+
+	// cached commands are specific to the backend, model and endpoint that
+	// generated them; the cache only sees redacted prompts and commands
+	backendKey := strings.Join([]string{backend.Name(), flags.GeneratorConf.Model, flags.GeneratorConf.URL, api.EndpointURL(api.EndpointGenerate)}, " ")
+	cached := cache.Wrap(backend, cache.Open(flags.CacheDir, flags.CacheLimits), backendKey, flags.NoCache)
+	return redact.Wrap(cached, redactor), nil
+}
+
+// the secrets redacted from what gen sent so far
+func Redactions(gen api.Generator) []redact.Redaction {
+	if g, ok := gen.(redact.Generator); ok {
+		return g.Redactor().Redactions()
+	}
+	return nil
+}
+
+// print the prompt as it was sent and what was redacted from it
+func ShowRedactions(gen api.Generator, prompt string) {
+	g, ok := gen.(redact.Generator)
+	if !ok {
+		return
+	}
+
+	fmt.Printf("Prompt as sent: %s\n", g.Redactor().Redact(prompt))
+	redactions := g.Redactor().Redactions()
+	if len(redactions) == 0 {
+		fmt.Println("Nothing was redacted.")
+		return
+	}
+
+	fmt.Printf("Redacted before sending (%d):\n", len(redactions))
+	for _, r := range redactions {
+		fmt.Printf("  %s  %s  %s\n", r.Placeholder, r.Rule, r.Masked)
+	}
+}
+
+// the environment context enabled in the config, empty if none is
+func EnvironmentContext(flags *FlagConfig) string {
+	if !flags.ContextConf.Any() {
+		return ""
+	}
+	return environment.Collect(flags.ContextConf).String()
+}
+
+// data piped to fl and files given with -f, delimited to be sent with the
+// prompt; piped stdin is spooled to a file that is returned so a command that
+// is run can read the same data
+func PromptData(flags *FlagConfig) (string, *os.File, error) {
+	sources := append([]*input.Source{}, flags.Sources...)
+
+	var stdin *os.File
+	if utils.IsPiped(os.Stdin) {
+		src, spool, err := input.ReadStdin(input.MaxBytes)
+		if err != nil {
+			return "", nil, fmt.Errorf("reading stdin: %v", err)
+		}
+		stdin = spool
+		if src.Incomplete != "" {
+			fmt.Printf("Warning: stopped reading stdin because %s, a command that is run only sees the first %d bytes.\n", src.Incomplete, src.Size)
+		}
+		if src.Size > 0 {
+			sources = append(sources, src)
+		}
+	}
+
+	for _, file := range flags.Files {
+		src, err := input.ReadFile(file, input.MaxBytes)
+		if err != nil {
+			return "", stdin, err
+		}
+		sources = append(sources, src)
+	}
+
+	return input.Format(sources), stdin, nil
+}
+
+// print the environment context that would be sent with prompts
+func ShowContext(flags *FlagConfig) {
+	if !flags.ContextConf.Any() {
+		fmt.Printf("No environment context is sent with prompts. Enable it with 'fl config set --context <categories>' (%s or all).\n", strings.Join(environment.Categories(), ", "))
+		return
+	}
+
+	fmt.Printf("Environment context sent with prompts (%s):\n\n", flags.ContextConf)
+	fmt.Print(EnvironmentContext(flags))
 }
 
 func exitAfterHelp(c *cobra.Command, exitCode int) {
@@ -1,14 +1,21 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fl/api"
+	"fl/config"
+	"fl/credentials"
+	"fl/environment"
+	"fl/profile"
+	"fl/utils"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
-func addConfCommand(rootCmd *cobra.Command, filepath string, flags *FlagConfig) {
+func addConfCommand(rootCmd *cobra.Command, flags *FlagConfig) {
 	configCmd := &cobra.Command{
 		Use:     "config",
 		Aliases: []string{"conf"},
@@ -17,10 +24,15 @@ func addConfCommand(rootCmd *cobra.Command, filepath string, flags *FlagConfig)
 		RunE: func(cmd *cobra.Command, args []string) error {
 			reset, _ := cmd.Flags().GetBool("reset")
 			if reset {
+				LoadFLID(flags)
 				flags.FLID = ""
-				flags.AutoExecuteConf = false
-				flags.LangtoolConf = ""
-				return writeConfig(filepath, *flags)
+				if err := writeFLID(*flags); err != nil {
+					return err
+				}
+				if err := flags.ConfigFile.Replace(nil); err != nil {
+					return err
+				}
+				return flags.ConfigFile.Save()
 			}
 			return cmd.Help()
 		},
@@ -30,17 +42,47 @@ func addConfCommand(rootCmd *cobra.Command, filepath string, flags *FlagConfig)
 	}
 
 	configGetSubCmd := &cobra.Command{
-		Use:   "get",
-		Short: "Get properties",
-		Args:  cobra.NoArgs,
-		Run: func(cmd *cobra.Command, args []string) {
+		Use:           "get [key]",
+		Short:         "Get properties",
+		Args:          cobra.MaximumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reveal, _ := cmd.Flags().GetBool("reveal")
+			if len(args) == 1 {
+				k, entry, err := config.Lookup(args[0])
+				if err != nil {
+					return err
+				}
+				value := flags.Settings.Get(k.Name).Value
+				if entry != "" {
+					value = flags.Settings.StringMap(k.Name)[entry]
+				}
+				fmt.Println(formatValue(k, value, reveal))
+				return nil
+			}
+
 			run, _ := cmd.Flags().GetBool("run")
 			langtool, _ := cmd.Flags().GetBool("langtool")
 			flid, _ := cmd.Flags().GetBool("flid")
-			all := !run && !langtool && !flid
+			backend, _ := cmd.Flags().GetBool("backend")
+			context, _ := cmd.Flags().GetBool("context")
+			all := !run && !langtool && !flid && !backend && !context
+
+			if all {
+				fmt.Println("profile:", flags.Profile)
+			}
 
 			if all || flid {
-				fmt.Println("flid:", flags.FLID)
+				LoadFLID(flags)
+				value := flags.FLID
+				if !reveal {
+					value = credentials.Mask(value)
+				}
+				if credentialStore != nil && storedFLID != "" {
+					value += fmt.Sprintf(" (%s credential store)", credentialStore.Name())
+				}
+				fmt.Println("flid:", value)
 			}
 
 			if all || run {
@@ -50,6 +92,16 @@ func addConfCommand(rootCmd *cobra.Command, filepath string, flags *FlagConfig)
 			if all || langtool {
 				fmt.Println("langtool:", flags.LangtoolConf)
 			}
+
+			if all || backend {
+				fmt.Println("backend:", flags.GeneratorConf.Backend)
+				fmt.Println("model:", flags.GeneratorConf.Model)
+			}
+
+			if all || context {
+				fmt.Println("context:", flags.ContextConf)
+			}
+			return nil
 		},
 		PostRun: func(cmd *cobra.Command, args []string) {
 			os.Exit(0)
@@ -57,15 +109,193 @@ func addConfCommand(rootCmd *cobra.Command, filepath string, flags *FlagConfig)
 	}
 
 	configSetSubCmd := &cobra.Command{
-		Use:           "set",
-		Short:         "Set properties",
+		Use:   "set [key value]",
+		Short: "Set properties",
+		Long: "Set a key of the profile's config file (see fl config list), e.g. fl config set generator.backend openai.\n" +
+			"Object keys take JSON or are set one entry at a time, e.g. fl config set api.paths.generate /api/generate.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 && len(args) != 2 {
+				return fmt.Errorf("expected a key and a value, got %d argument(s)", len(args))
+			}
+			return nil
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 2 {
+				if err := setKey(flags.ConfigFile, args[0], args[1]); err != nil {
+					return err
+				}
+				return flags.ConfigFile.Save()
+			}
+
+			// the shorthand flags only change the keys they are given for
+			shorthands := map[string]string{"run": "run", "langtool": "langtool", "backend": "generator.backend", "model": "generator.model"}
+			changed := false
+			for flag, key := range shorthands {
+				if cmd.Flags().Changed(flag) {
+					if err := setKey(flags.ConfigFile, key, cmd.Flag(flag).Value.String()); err != nil {
+						return err
+					}
+					changed = true
+				}
+			}
+			if cmd.Flags().Changed("context") {
+				context, _ := cmd.Flags().GetString("context")
+				toggles, err := environment.ParseToggles(context)
+				if err != nil {
+					return err
+				}
+				for _, c := range environment.Categories() {
+					flags.ConfigFile.Set("context."+c, toggles[c])
+				}
+				changed = true
+			}
+
+			if !changed {
+				return cmd.Help()
+			}
+			return flags.ConfigFile.Save()
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	configUnsetSubCmd := &cobra.Command{
+		Use:           "unset <key>...",
+		Short:         "Remove properties so their defaults apply",
+		Args:          cobra.MinimumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, name := range args {
+				if _, _, err := config.Lookup(name); err != nil {
+					return err
+				}
+				flags.ConfigFile.Unset(name)
+			}
+			return flags.ConfigFile.Save()
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	configListSubCmd := &cobra.Command{
+		Use:           "list",
+		Aliases:       []string{"ls"},
+		Short:         "List all properties and where their values come from",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			reveal, _ := cmd.Flags().GetBool("reveal")
+
+			// flags of the command line override the run and langtool keys
+			if cmd.Flags().Changed("run") {
+				flags.Settings.Override("run", flags.AutoExecute, config.FromFlag)
+			}
+			if cmd.Flags().Changed("langtool") {
+				flags.Settings.Override("langtool", flags.Langtool, config.FromFlag)
+			}
+
+			fmt.Printf("# profile %s: %s\n", flags.Profile, flags.ConfigFile.Path())
+			for _, v := range flags.Settings.Values() {
+				source := v.Source
+				if v.Source == config.FromEnv {
+					source += " " + v.Key.Env
+				}
+				fmt.Printf("%-24s %-40s %s\n", v.Key.Name, formatValue(v.Key, v.Value, reveal), source)
+			}
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	configEditSubCmd := &cobra.Command{
+		Use:           "edit",
+		Short:         "Edit the profile's config file in $EDITOR",
 		Args:          cobra.NoArgs,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			flags.AutoExecuteConf, _ = cmd.Flags().GetBool("run")
-			flags.LangtoolConf, _ = cmd.Flags().GetString("langtool")
-			return writeConfig(filepath, *flags)
+			raw, err := flags.ConfigFile.Marshal()
+			if err != nil {
+				return err
+			}
+
+			// invalid edits are offered back to the editor instead of being saved
+			text := string(raw)
+			for {
+				text, err = utils.EditInEditor(text, ".json")
+				if err != nil {
+					return err
+				}
+				err = flags.ConfigFile.Replace([]byte(text))
+				if err == nil {
+					break
+				}
+				fmt.Fprintln(os.Stderr, err)
+				if !utils.PromptYesNo("Edit again?") {
+					return fmt.Errorf("the config was not changed")
+				}
+			}
+			return flags.ConfigFile.Save()
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	configSchemaSubCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the config file",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println(string(config.Schema()))
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	configEndpointsSubCmd := &cobra.Command{
+		Use:           "endpoints",
+		Short:         "Show the API endpoints in use",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if check, _ := cmd.Flags().GetBool("check"); !check {
+				for _, name := range api.EndpointNames() {
+					url := api.EndpointURL(name)
+					if url == "" {
+						url = "not configured"
+					}
+					fmt.Printf("%-20s %s\n", name+":", url)
+				}
+				return nil
+			}
+
+			unreachable := 0
+			for _, status := range api.CheckEndpoints(cmd.Context()) {
+				if status.Reachable() {
+					fmt.Printf("%-20s %s reachable (HTTP %d, %v)\n", status.Name+":", status.URL, status.StatusCode, status.Latency.Round(time.Millisecond))
+				} else if status.Err != nil {
+					unreachable++
+					fmt.Printf("%-20s %s unreachable: %v\n", status.Name+":", status.URL, status.Err)
+				} else {
+					unreachable++
+					fmt.Printf("%-20s %s unreachable (HTTP %d)\n", status.Name+":", status.URL, status.StatusCode)
+				}
+			}
+
+			if unreachable > 0 {
+				return fmt.Errorf("%d endpoint(s) are not reachable", unreachable)
+			}
+			return nil
 		},
 		PostRun: func(cmd *cobra.Command, args []string) {
 			os.Exit(0)
@@ -78,39 +308,227 @@ func addConfCommand(rootCmd *cobra.Command, filepath string, flags *FlagConfig)
 	configGetSubCmd.PersistentFlags().BoolP("run", "r", false, "Get auto-execute setting")
 	configGetSubCmd.PersistentFlags().BoolP("langtool", "l", false, "Get shell or tool setting")
 	configGetSubCmd.PersistentFlags().BoolP("flid", "f", false, "Get login info")
+	configGetSubCmd.PersistentFlags().Bool("reveal", false, "Show the FLID and secrets instead of masking them")
+	configGetSubCmd.PersistentFlags().BoolP("backend", "b", false, "Get generation backend and model")
+	configGetSubCmd.PersistentFlags().BoolP("context", "c", false, "Get environment context categories")
 
 	configCmd.AddCommand(configSetSubCmd)
-	configSetSubCmd.PersistentFlags().BoolP("run", "r", flags.AutoExecuteConf, "Set auto-execute")
-	configSetSubCmd.PersistentFlags().StringP("langtool", "l", flags.LangtoolConf, "Set default shell or a tool or use")
+	configSetSubCmd.PersistentFlags().BoolP("run", "r", false, "Set auto-execute (same as run true)")
+	configSetSubCmd.PersistentFlags().StringP("langtool", "l", "", "Set default shell or a tool or use (same as langtool <name>)")
+	configSetSubCmd.PersistentFlags().StringP("backend", "b", "", "Set generation backend: flows, openai or local (same as generator.backend <name>)")
+	configSetSubCmd.PersistentFlags().StringP("model", "m", "", "Set model used by the openai or local backend (same as generator.model <name>)")
+	configSetSubCmd.PersistentFlags().StringP("context", "c", "", "Set environment context sent with prompts (comma separated: os, coreutils, shell, tools, files, versions, all or none)")
+
+	configCmd.AddCommand(configUnsetSubCmd)
+
+	configCmd.AddCommand(configListSubCmd)
+	configListSubCmd.PersistentFlags().Bool("reveal", false, "Show secrets instead of masking them")
+
+	configCmd.AddCommand(configEditSubCmd)
+
+	configCmd.AddCommand(configSchemaSubCmd)
+
+	configCmd.AddCommand(configEndpointsSubCmd)
+	configEndpointsSubCmd.PersistentFlags().BoolP("check", "c", false, "Check that the endpoints are reachable")
 
 	rootCmd.AddCommand(configCmd)
 }
 
-func ReadConfig(filepath string, flags *FlagConfig) error {
-	if _, err := os.Stat(filepath); os.IsNotExist(err) {
+// set a key, or one entry of an object key, from its text form
+func setKey(file *config.File, name string, text string) error {
+	k, entry, err := config.Lookup(name)
+	if err != nil {
+		return err
+	}
+	if entry != "" {
+		if err := k.CheckEntry(entry); err != nil {
+			return err
+		}
+		file.Set(name, text)
 		return nil
 	}
 
-	viper.SetConfigFile(filepath)
-	viper.SetConfigType("json")
-	err := viper.ReadInConfig()
+	value, err := k.Parse(text)
+	if err != nil {
+		return err
+	}
+	file.Set(k.Name, value)
+	return nil
+}
+
+// a value as listed, secrets are masked unless revealed
+func formatValue(k config.Key, value interface{}, reveal bool) string {
+	switch v := value.(type) {
+	case string:
+		if k.Secret && !reveal {
+			return credentials.Mask(v)
+		}
+		if v == "" {
+			return `""`
+		}
+		return v
+	case map[string]string:
+		if len(v) == 0 {
+			return "{}"
+		}
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+	return fmt.Sprint(value)
+}
+
+func ReadConfig(filepath string, flags *FlagConfig) error {
+	file, err := config.Load(filepath)
 	if err != nil {
 		return err
 	}
+	settings, err := config.Resolve(file)
+	if err != nil {
+		return err
+	}
+	flags.ConfigFile, flags.Settings = file, settings
+
+	flags.AutoExecuteConf = settings.Bool("run")
+	flags.LangtoolConf = settings.String("langtool")
+	flags.CredentialStoreConf = settings.String("credentials.store")
+
+	flags.GeneratorConf.Backend = settings.String("generator.backend")
+	flags.GeneratorConf.Model = settings.String("generator.model")
+	flags.GeneratorConf.URL = settings.String("generator.url")
+	flags.GeneratorConf.APIKey = settings.String("generator.apikey")
+	flags.GeneratorConf.Temperature = settings.Float("generator.temperature")
+	flags.GeneratorConf.MaxTokens = settings.Int("generator.maxtokens")
+
+	flags.ContextConf = environment.Toggles{}
+	for _, c := range environment.Categories() {
+		flags.ContextConf[c] = settings.Bool("context." + c)
+	}
+
+	flags.RedactConf.Disabled = settings.Bool("redact.disabled")
+	flags.RedactConf.Emails = settings.Bool("redact.emails")
+	flags.RedactConf.Hostnames = settings.Bool("redact.hostnames")
+	flags.RedactConf.Patterns = settings.StringMap("redact.patterns")
+
+	flags.EndpointsConf.BaseURL = settings.String("api.baseurl")
+	flags.EndpointsConf.Paths = settings.StringMap("api.paths")
+	readEndpointsFromEnv(flags)
+
+	flags.HTTPConf.Timeout = time.Duration(settings.Int("http.timeout")) * time.Second
+	flags.HTTPConf.Retries = settings.Int("http.retries")
+	if flags.HTTPConf.Retries == 0 {
+		flags.HTTPConf.Retries = -1
+	}
+	flags.HTTPConf.Proxy = settings.String("http.proxy")
+	flags.HTTPConf.CAFile = settings.String("http.cafile")
+	flags.HTTPConf.CertFile = settings.String("http.certfile")
+	flags.HTTPConf.KeyFile = settings.String("http.keyfile")
 
-	flags.AutoExecuteConf = viper.GetBool("run")
-	flags.LangtoolConf = viper.GetString("langtool")
-	flags.FLID = viper.GetString("flid")
+	flags.HistoryRetention.MaxEntries = settings.Int("history.maxentries")
+	flags.HistoryRetention.MaxAge = time.Duration(settings.Int("history.maxdays")) * 24 * time.Hour
 
+	flags.CacheLimits.TTL = time.Duration(settings.Int("cache.ttlhours")) * time.Hour
+	flags.CacheLimits.MaxBytes = int64(settings.Int("cache.maxmb")) * 1024 * 1024
 	return nil
 }
 
-func writeConfig(filepath string, flags FlagConfig) error {
-	viper.Set("run", flags.AutoExecuteConf)
-	viper.Set("langtool", flags.LangtoolConf)
-	viper.Set("flid", flags.FLID)
+// FL_API_<NAME> overrides the path of an endpoint, the base URL is the
+// api.baseurl key and overridden by FL_API_URL
+func readEndpointsFromEnv(flags *FlagConfig) {
+	overridden := false
+	for _, name := range api.EndpointNames() {
+		if path := os.Getenv(api.EndpointEnv(name)); path != "" {
+			flags.EndpointsConf.Paths[name] = path
+			overridden = true
+		}
+	}
+
+	if overridden {
+		flags.Settings.Override("api.paths", flags.EndpointsConf.Paths, config.FromEnv)
+	}
+}
+
+// the credential store and the FLID in it when the config was read
+var (
+	credentialStore credentials.Store
+	storedFLID      string
+)
+
+func openCredentialStore(flags *FlagConfig) (credentials.Store, error) {
+	if credentialStore == nil {
+		store, err := credentials.Open(flags.CredentialStoreConf, flags.Profiles.CredentialsFile())
+		if err != nil {
+			return nil, err
+		}
+		credentialStore = store
+	}
+	return credentialStore, nil
+}
+
+// read the FLID on the first call, only commands that send it call this so
+// the others never unlock the credential store
+func LoadFLID(flags *FlagConfig) {
+	if flidLoaded {
+		return
+	}
+	flidLoaded = true
+	readFLID(flags)
+}
+
+var flidLoaded bool
+
+// read the FLID from the credential store, moving a FLID that is still in
+// the config file into the store first; a store that cannot be used is
+// reported without stopping fl
+func readFLID(flags *FlagConfig) {
+	file := flags.ConfigFile
+	plain, _ := file.Get("flid")
+	flags.FLID, _ = plain.(string)
+
+	store, err := openCredentialStore(flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot open the credential store: %v\n", err)
+		return
+	}
+
+	if flags.FLID != "" {
+		if err := store.Set(profile.FLIDKey(flags.Profile), flags.FLID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: the FLID is still saved in plain text in %s: %v\n", file.Path(), err)
+			return
+		}
+		file.Unset("flid")
+		if err := file.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot remove the FLID from %s: %v\n", file.Path(), err)
+		}
+	}
+
+	flid, err := store.Get(profile.FLIDKey(flags.Profile))
+	if err != nil && err != credentials.ErrNotFound {
+		fmt.Fprintf(os.Stderr, "Warning: cannot read the FLID from the %s credential store: %v\n", store.Name(), err)
+		return
+	}
+	flags.FLID, storedFLID = flid, flid
+}
+
+// save a new FLID in the credential store, or delete it after a reset
+func writeFLID(flags FlagConfig) error {
+	if flags.FLID == storedFLID {
+		return nil
+	}
+
+	store, err := openCredentialStore(&flags)
+	if err != nil {
+		return err
+	}
 
-	viper.SetConfigFile(filepath)
-	viper.SetConfigType("json")
-	return viper.WriteConfig()
+	if flags.FLID == "" {
+		err = store.Delete(profile.FLIDKey(flags.Profile))
+	} else {
+		err = store.Set(profile.FLIDKey(flags.Profile), flags.FLID)
+	}
+	if err != nil {
+		return fmt.Errorf("saving the FLID in the %s credential store: %v", store.Name(), err)
+	}
+
+	storedFLID = flags.FLID
+	return nil
 }
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fl/api"
+	"fl/fakeflows"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func addDevCommand(rootCmd *cobra.Command) {
+	devCmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Development tools",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	fakeServerCmd := &cobra.Command{
+		Use:           "fake-server",
+		Short:         "Run a fake Flows server for offline development",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, _ := cmd.Flags().GetString("addr")
+			flids, _ := cmd.Flags().GetStringSlice("flid")
+			quota, _ := cmd.Flags().GetInt("quota")
+			status, _ := cmd.Flags().GetString("status")
+			command, _ := cmd.Flags().GetString("command")
+
+			server, err := fakeflows.New(addr)
+			if err != nil {
+				return err
+			}
+			defer server.Close()
+
+			for _, flid := range flids {
+				server.AddUser(fakeflows.User{FLID: flid, Quota: quota, Status: status})
+			}
+			if command != "" {
+				server.SetCommand("", command)
+			}
+
+			fmt.Println("Fake Flows server listening on", server.URL)
+			for _, e := range []fakeflows.Endpoint{
+				fakeflows.Generate,
+				fakeflows.Explain,
+				fakeflows.LoginGuest,
+				fakeflows.LoginGitHub,
+				fakeflows.StartSubscription,
+				fakeflows.CancelSubscription,
+				fakeflows.StatusOfSubscription,
+			} {
+				fmt.Printf("  %-20s %s\n", e, server.EndpointURL(e))
+			}
+			fmt.Println("Point fl at it with: export FL_API_URL=" + server.URL)
+			fmt.Printf("and %s=%s for --explain\n", api.EndpointEnv(api.EndpointExplain), fakeflows.ExplainPath)
+			fmt.Println("Press Ctrl-C to stop.")
+
+			<-cmd.Context().Done()
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	fakeServerCmd.PersistentFlags().String("addr", "127.0.0.1:8787", "Address to listen on")
+	fakeServerCmd.PersistentFlags().StringSlice("flid", []string{"fake-flid"}, "Register users with these FLIDs")
+	fakeServerCmd.PersistentFlags().Int("quota", fakeflows.DefaultQuota, "Generations allowed per user before the quota is exhausted (negative for unlimited)")
+	fakeServerCmd.PersistentFlags().String("status", "default", "Subscription status of registered users (guest, default, paid or canceling)")
+	fakeServerCmd.PersistentFlags().String("command", "", "Command returned for every prompt")
+
+	devCmd.AddCommand(fakeServerCmd)
+	rootCmd.AddCommand(devCmd)
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fl/history"
+	"fl/input"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// a command that failed and what is known about how it failed
+type failedCommand struct {
+	cmd      string
+	status   int // -1 if unknown
+	stderr   string
+	langtool string
+}
+
+// the request sent to the backend, the stderr is sent as data
+func (f failedCommand) prompt() string {
+	status := "failed"
+	if f.status > 0 {
+		status = fmt.Sprintf("failed with exit status %d", f.status)
+	}
+	return fmt.Sprintf("This command %s, correct it: %s", status, f.cmd)
+}
+
+func addFixCommand(rootCmd *cobra.Command, flags *FlagConfig) {
+	fixCmd := &cobra.Command{
+		Use:   "fix [command]",
+		Short: "Propose a corrected version of the last failed command",
+		Long: `Propose a corrected version of a command that failed, which is then handled like
+any generated command: it can be copied, explained, edited and run.
+
+The command is the one given as arguments, else the last command that failed in
+the shell (recorded by the hook from 'fl init'), else the last command fl ran that
+failed. Its stderr is
+used when fl ran it, when it is given with --stderr, or when it is piped to fl fix:
+
+  make 2>&1 | fl fix make`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// passed by the fl function of the shell hook
+			recorded := failedCommand{status: -1}
+			recorded.cmd, _ = cmd.Flags().GetString("last-command")
+			recorded.status, _ = cmd.Flags().GetInt("last-status")
+			recorded.langtool, _ = cmd.Flags().GetString("last-shell")
+
+			failed, err := lastFailed(flags, args, recorded)
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("status") {
+				failed.status, _ = cmd.Flags().GetInt("status")
+			}
+			if failed.status == 0 {
+				return fmt.Errorf("'%s' succeeded, there is nothing to fix", failed.cmd)
+			}
+			if stderr, _ := cmd.Flags().GetString("stderr"); stderr != "" {
+				failed.stderr = stderr
+			}
+			if failed.langtool != "" && !cmd.Flags().Changed("langtool") {
+				flags.Langtool = failed.langtool
+			}
+
+			// continues as a regular generation for the prompt
+			flags.Prompt = failed.prompt()
+			if failed.stderr != "" {
+				src, err := input.Read("stderr", strings.NewReader(failed.stderr), input.MaxBytes)
+				if err != nil {
+					return err
+				}
+				flags.Sources = append(flags.Sources, src)
+			}
+			return nil
+		},
+	}
+
+	fixCmd.Flags().Int("status", 0, "Exit status of the command")
+	fixCmd.Flags().String("stderr", "", "What the command wrote to stderr")
+	fixCmd.Flags().String("last-command", "", "Last failed command in the shell")
+	fixCmd.Flags().Int("last-status", -1, "Exit status of the last failed command in the shell")
+	fixCmd.Flags().String("last-shell", "", "Shell of the last failed command")
+	for _, name := range []string{"last-command", "last-status", "last-shell"} {
+		fixCmd.Flags().MarkHidden(name)
+	}
+
+	rootCmd.AddCommand(fixCmd)
+}
+
+// the command to fix from the arguments, the one recorded by the shell hook or
+// the history; the stderr of a command fl ran is taken from the history
+func lastFailed(flags *FlagConfig, args []string, recorded failedCommand) (*failedCommand, error) {
+	entries, err := history.Open(flags.HistoryFile, flags.HistoryRetention).List()
+	if err != nil {
+		return nil, err
+	}
+
+	failed := &failedCommand{status: -1}
+	switch {
+	case len(args) > 0:
+		failed.cmd = strings.Join(args, " ")
+
+	// a failed fl run is the command it ran, which is in the history
+	case recorded.cmd != "" && !strings.HasPrefix(recorded.cmd, "fl "):
+		*failed = recorded
+
+	default:
+		for i := len(entries) - 1; i >= 0; i-- {
+			if e := entries[i]; e.Executed && e.ExitCode != 0 {
+				failed.cmd, failed.status, failed.langtool = e.Cmd, e.ExitCode, e.Langtool
+				break
+			}
+		}
+		if failed.cmd == "" {
+			return nil, fmt.Errorf("no command to fix, pass it as arguments or install the shell hook with 'fl init'")
+		}
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if e := entries[i]; e.Executed && e.Cmd == failed.cmd {
+			failed.stderr = e.Stderr
+			if failed.status < 0 {
+				failed.status = e.ExitCode
+			}
+			if failed.langtool == "" {
+				failed.langtool = e.Langtool
+			}
+			break
+		}
+	}
+
+	return failed, nil
+}
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"fl/exec"
+	"fl/history"
+	"fl/langtool"
+	"fl/safety"
+	"fl/utils"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func addHistoryCommand(rootCmd *cobra.Command, flags *FlagConfig) {
+	store := func() *history.Store {
+		return history.Open(flags.HistoryFile, flags.HistoryRetention)
+	}
+
+	historyCmd := &cobra.Command{
+		Use:     "history",
+		Aliases: []string{"hist"},
+		Short:   "Generated command history",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:           "list",
+		Aliases:       []string{"ls"},
+		Short:         "List recent commands",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := store().List()
+			if err != nil {
+				return err
+			}
+
+			if n, _ := cmd.Flags().GetInt("number"); n > 0 && len(entries) > n {
+				entries = entries[len(entries)-n:]
+			}
+			printEntries(entries)
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	showCmd := &cobra.Command{
+		Use:           "show <id>",
+		Short:         "Show a command and how it was used",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := getEntry(store(), args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("id:", e.ID)
+			fmt.Println("time:", e.Timestamp.Format(time.RFC1123))
+			fmt.Println("prompt:", e.Prompt)
+			fmt.Println("langtool:", e.Langtool)
+			fmt.Println("command:", e.Cmd)
+			fmt.Println("edited:", e.Edited)
+			fmt.Println("copied:", e.Copied)
+			fmt.Println("executed:", e.Executed)
+			if e.Executed {
+				fmt.Println("exit status:", e.ExitCode)
+			}
+			fmt.Println("duration:", e.Duration.Round(time.Millisecond))
+			if e.Stderr != "" {
+				fmt.Printf("stderr:\n%s\n", strings.TrimRight(e.Stderr, "\n"))
+			}
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	searchCmd := &cobra.Command{
+		Use:           "search <words>",
+		Aliases:       []string{"find"},
+		Short:         "Search prompts and commands",
+		Args:          cobra.MinimumNArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := store().Search(strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+
+			printEntries(entries)
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	rerunCmd := &cobra.Command{
+		Use:           "rerun <id>",
+		Short:         "Execute a command from the history again",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := getEntry(store(), args[0])
+			if err != nil {
+				return err
+			}
+
+			lt, err := langtool.Lookup(e.Langtool)
+			if err != nil {
+				return err
+			}
+			if !lt.Executable() {
+				return fmt.Errorf("%s commands cannot be executed", lt.Name)
+			}
+
+			fmt.Println(e.Cmd)
+
+			if report := safety.Analyze(e.Cmd, lt.Name); report.Dangerous() {
+				fmt.Printf("\n%s\n", report)
+				if !utils.PromptConfirm("Would you like to execute the command?", safety.ConfirmPhrase) {
+					return nil
+				}
+			} else if len(report.Notices) > 0 {
+				fmt.Printf("\n%s\n", report)
+			}
+
+			start := time.Now()
+			code, stderr, err := exec.CommandWithShell(lt.Shell, e.Cmd).StreamCapture(cmd.Context())
+
+			rerun := history.Entry{Prompt: e.Prompt, Langtool: lt.Name, Cmd: e.Cmd, Executed: true, ExitCode: code, Stderr: stderr, Duration: time.Since(start)}
+			if _, err := store().Add(rerun); err != nil {
+				utils.Log(flags.Verbose, "Error saving history: %v\n", err)
+			}
+
+			if err != nil {
+				return fmt.Errorf("error while executing command: %s", err)
+			}
+
+			if err := cmd.Context().Err(); err != nil {
+				return err
+			}
+
+			// exit with the command's status like a regular run
+			if code != 0 {
+				os.Exit(code)
+			}
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:           "delete <id>",
+		Aliases:       []string{"rm"},
+		Short:         "Delete a command from the history",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid history id '%s'", args[0])
+			}
+			return store().Delete(id)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	clearCmd := &cobra.Command{
+		Use:           "clear",
+		Short:         "Delete the entire history",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if force, _ := cmd.Flags().GetBool("force"); !force && !utils.PromptYesNo("Delete the entire history?") {
+				return nil
+			}
+			return store().Clear()
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	listCmd.PersistentFlags().IntP("number", "n", 20, "Number of recent commands to list (0 for all)")
+	clearCmd.PersistentFlags().BoolP("force", "f", false, "Do not ask for confirmation")
+
+	historyCmd.AddCommand(listCmd)
+	historyCmd.AddCommand(showCmd)
+	historyCmd.AddCommand(searchCmd)
+	historyCmd.AddCommand(rerunCmd)
+	historyCmd.AddCommand(deleteCmd)
+	historyCmd.AddCommand(clearCmd)
+
+	rootCmd.AddCommand(historyCmd)
+}
+
+func getEntry(store *history.Store, arg string) (*history.Entry, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid history id '%s'", arg)
+	}
+	return store.Get(id)
+}
+
+func printEntries(entries []history.Entry) {
+	for _, e := range entries {
+		status := ""
+		if e.Executed {
+			status = fmt.Sprintf("ran (%d)", e.ExitCode)
+		} else if e.Copied {
+			status = "copied"
+		}
+
+		fmt.Printf("%4d  %s  %-9s %s\n", e.ID, e.Timestamp.Format("2006-01-02 15:04"), status, e.Cmd)
+		fmt.Printf("      # %s\n", e.Prompt)
+	}
+}
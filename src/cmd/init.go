@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// a shell widget that replaces the command line with the command generated
+// for it, so the command can be reviewed and lands in the shell's own history,
+// and a hook that records the last failed command and its status for fl fix;
+// they are kept in unexported shell variables, since command lines may hold
+// secrets, and a wrapper function passes them to fl fix only
+type widget struct {
+	key     string // default key binding in the shell's notation
+	snippet string // FL_KEY is replaced by the key binding
+	hook    string
+}
+
+var widgets = map[string]widget{
+	"bash": {
+		key: `\C-g`,
+		snippet: `# fl shell integration for bash, add to ~/.bashrc:
+#   eval "$(fl init bash)"
+__fl_widget() {
+  [ -z "$READLINE_LINE" ] && return
+  local cmd
+  cmd="$(command fl --widget -l bash -- "$READLINE_LINE")" || return
+  if [ -n "$cmd" ]; then
+    READLINE_LINE="$cmd"
+    READLINE_POINT=${#READLINE_LINE}
+  fi
+}
+bind -x '"FL_KEY": __fl_widget'
+`,
+		hook: `__fl_record() {
+  local last_status=$? cmd
+  [ $last_status -eq 0 ] && return 0
+  cmd="$(HISTTIMEFORMAT= builtin history 1)"
+  cmd="${cmd#*[0-9]  }"
+  case "$cmd" in
+    ""|"fl fix"*) ;;
+    *) __fl_last_command="$cmd" __fl_last_status=$last_status ;;
+  esac
+  return $last_status
+}
+PROMPT_COMMAND="__fl_record${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fl() {
+  if [ "$1" = fix ] && [ -n "$__fl_last_command" ]; then
+    shift
+    command fl fix "--last-command=$__fl_last_command" "--last-status=$__fl_last_status" --last-shell=bash "$@"
+  else
+    command fl "$@"
+  fi
+}
+`,
+	},
+	"zsh": {
+		key: `^G`,
+		snippet: `# fl shell integration for zsh, add to ~/.zshrc:
+#   eval "$(fl init zsh)"
+__fl_widget() {
+  [[ -z "$BUFFER" ]] && return
+  local cmd
+  zle -I
+  cmd="$(command fl --widget -l zsh -- "$BUFFER")"
+  if [[ -n "$cmd" ]]; then
+    BUFFER="$cmd"
+    CURSOR=${#BUFFER}
+  fi
+  zle reset-prompt
+}
+zle -N __fl_widget
+bindkey 'FL_KEY' __fl_widget
+`,
+		hook: `__fl_preexec() {
+  __fl_cmd="$1"
+}
+__fl_precmd() {
+  local last_status=$?
+  if [[ $last_status -ne 0 ]]; then
+    case "$__fl_cmd" in
+      ""|"fl fix"*) ;;
+      *) __fl_last_command="$__fl_cmd" __fl_last_status=$last_status ;;
+    esac
+  fi
+  __fl_cmd=
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook preexec __fl_preexec
+add-zsh-hook precmd __fl_precmd
+fl() {
+  if [[ "$1" == fix && -n "$__fl_last_command" ]]; then
+    shift
+    command fl fix "--last-command=$__fl_last_command" "--last-status=$__fl_last_status" --last-shell=zsh "$@"
+  else
+    command fl "$@"
+  fi
+}
+`,
+	},
+	"fish": {
+		key: `\cg`,
+		snippet: `# fl shell integration for fish, add to ~/.config/fish/config.fish:
+#   fl init fish | source
+function __fl_widget
+    set -l buffer (commandline)
+    test -z "$buffer"; and return
+    set -l cmd (command fl --widget -l fish -- "$buffer" | string collect)
+    if test -n "$cmd"
+        commandline -r -- $cmd
+        commandline -C (string length -- $cmd)
+    end
+    commandline -f repaint
+end
+bind FL_KEY __fl_widget
+bind -M insert FL_KEY __fl_widget
+`,
+		hook: `function __fl_record --on-event fish_postexec
+    set -l last_status $status
+    test $last_status -eq 0; and return
+    string match -q -- 'fl fix*' $argv[1]; and return
+    test -z "$argv[1]"; and return
+    set -g __fl_last_command $argv[1]
+    set -g __fl_last_status $last_status
+end
+function fl
+    set -l rest $argv
+    if test "$argv[1]" = fix; and set -q __fl_last_command
+        set -e rest[1]
+        command fl fix --last-command=$__fl_last_command --last-status=$__fl_last_status --last-shell=fish $rest
+    else
+        command fl $rest
+    end
+end
+`,
+	},
+}
+
+func widgetShells() []string {
+	shells := []string{}
+	for shell := range widgets {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+	return shells
+}
+
+func addInitCommand(rootCmd *cobra.Command) {
+	initCmd := &cobra.Command{
+		Use:   "init <shell>",
+		Short: "Print shell integration that turns the command line into a command with a key press",
+		Long: `Print a snippet to source in your shell's rc file. It binds a key (Ctrl-G by default)
+that sends the current command line to fl as the prompt and replaces it with the
+generated command, without running it. It also records the last failed command
+and its exit status for 'fl fix'.
+
+  bash: eval "$(fl init bash)"
+  zsh:  eval "$(fl init zsh)"
+  fish: fl init fish | source`,
+		Args:          cobra.ExactArgs(1),
+		ValidArgs:     widgetShells(),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w, ok := widgets[strings.ToLower(args[0])]
+			if !ok {
+				return fmt.Errorf("unsupported shell '%s' (supported: %s)", args[0], strings.Join(widgetShells(), ", "))
+			}
+
+			key, _ := cmd.Flags().GetString("key")
+			if key == "" {
+				key = w.key
+			}
+
+			fmt.Print(strings.ReplaceAll(w.snippet, "FL_KEY", key))
+			if hook, _ := cmd.Flags().GetBool("hook"); hook {
+				fmt.Print(w.hook)
+			}
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	initCmd.PersistentFlags().Bool("hook", true, "Record the last failed command and its exit status for 'fl fix'")
+	initCmd.PersistentFlags().String("key", "", `Key binding in the shell's notation (default: \C-g for bash, ^G for zsh, \cg for fish)`)
+
+	rootCmd.AddCommand(initCmd)
+}
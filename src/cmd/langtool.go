@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fl/langtool"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func addLangtoolCommand(rootCmd *cobra.Command) {
+	langtoolCmd := &cobra.Command{
+		Use:   "langtools",
+		Short: "List supported shells and tools",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, lt := range langtool.All() {
+				name := lt.Name
+				if len(lt.Aliases) > 0 {
+					name += " (" + strings.Join(lt.Aliases, ", ") + ")"
+				}
+				if lt.Name == langtool.Default {
+					name += " [default]"
+				}
+				fmt.Printf("  %-30s %s\n", name, lt.Description)
+			}
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	rootCmd.AddCommand(langtoolCmd)
+}
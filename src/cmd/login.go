@@ -1,13 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fl/api"
 	"fl/utils"
 	"fmt"
 )
 
-func loginGitHub(verbose bool, githubClientId string) (string, error) {
-	token, err := utils.GetGitHubAccessToken(githubClientId)
+func loginGitHub(ctx context.Context, verbose bool, githubClientId string) (string, error) {
+	token, err := utils.GetGitHubAccessToken(ctx, githubClientId)
 	if err != nil {
 		return "", err
 	}
@@ -21,7 +22,7 @@ func loginGitHub(verbose bool, githubClientId string) (string, error) {
 		fmt.Println("GitHub access token:", token.AccessToken)
 	}
 
-	flid, err := api.LoginCommand(token.AccessToken)
+	flid, err := api.LoginCommand(ctx, token.AccessToken)
 	if err != nil {
 		return "", err
 	}
@@ -29,8 +30,8 @@ func loginGitHub(verbose bool, githubClientId string) (string, error) {
 	return flid, nil
 }
 
-func loginGuest() (string, error) {
-	flid, err := api.LoginGuestUserByIP()
+func loginGuest(ctx context.Context) (string, error) {
+	flid, err := api.LoginGuestUserByIP(ctx)
 	if err != nil {
 		err = fmt.Errorf("failed to get a Guest access token: %v", err)
 		return "", err
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fl/tldr"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// number of offline suggestions shown for a prompt
+const offlineSuggestions = 3
+
+func addLookupCommand(rootCmd *cobra.Command, flags *FlagConfig) {
+	var list bool
+	var importPath string
+
+	lookupCmd := &cobra.Command{
+		Use:     "lookup <tool> [subcommand]",
+		Aliases: []string{"tldr"},
+		Short:   "Show the cheat sheet of a tool from the offline command corpus",
+		Long: `Show the cheat sheet of a tool from the offline command corpus.
+
+The corpus is used when the API cannot be reached, when the quota is
+exhausted, or with fl --offline. fl bundles pages for common tools. Pages in
+the tldr-pages format can be imported from a checkout of
+https://github.com/tldr-pages/tldr with --import, and replace bundled pages
+of the same name.`,
+		Args:          cobra.ArbitraryArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := tldrDir(flags)
+			if importPath != "" {
+				n, err := tldr.Import(importPath, dir)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Imported %d pages into %s\n", n, dir)
+				return nil
+			}
+
+			corpus, err := tldr.Load(dir)
+			if err != nil {
+				return err
+			}
+
+			if list {
+				for _, p := range corpus.Pages() {
+					fmt.Printf("  %-20s %s\n", p.Name, p.Description)
+				}
+				return nil
+			}
+
+			if len(args) == 0 {
+				return fmt.Errorf("name a tool to look up, for example: fl lookup tar")
+			}
+
+			name := strings.Join(args, "-")
+			page := corpus.Page(name)
+			if page == nil {
+				similar := corpus.Similar(name, 3)
+				if len(similar) > 0 {
+					return fmt.Errorf("no page for '%s' in the offline corpus, did you mean %s?", name, strings.Join(similar, ", "))
+				}
+				return fmt.Errorf("no page for '%s' in the offline corpus", name)
+			}
+
+			fmt.Print(page)
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	lookupCmd.Flags().BoolVar(&list, "list", false, "List the tools in the corpus")
+	lookupCmd.Flags().StringVar(&importPath, "import", "", "Import tldr pages from a tldr-pages checkout, a directory of pages or a page")
+	rootCmd.AddCommand(lookupCmd)
+}
+
+// imported tldr pages are kept with the configuration
+func tldrDir(flags *FlagConfig) string {
+	if flags.Profiles == nil {
+		return ""
+	}
+	return filepath.Join(flags.Profiles.Dir(), "tldr")
+}
+
+// the examples of the offline corpus that best match the prompt; a page
+// named after the langtool, e.g. git or jq, is preferred
+func OfflineSuggestions(flags *FlagConfig) ([]tldr.Match, error) {
+	corpus, err := tldr.Load(tldrDir(flags))
+	if err != nil {
+		return nil, err
+	}
+
+	query := flags.Prompt
+	if corpus.Page(flags.Langtool) != nil {
+		query += " " + flags.Langtool
+	}
+	return corpus.Search(query, offlineSuggestions), nil
+}
+
+// list offline suggestions under a label that sets them apart from generated commands
+func ShowOfflineSuggestions(w io.Writer, matches []tldr.Match) {
+	if len(matches) == 0 {
+		fmt.Fprintln(w, "No offline suggestion matches the prompt. Run 'fl lookup --list' to see the tools in the offline corpus.")
+		return
+	}
+
+	fmt.Fprintln(w, "Offline suggestions from the local command corpus, not generated for your prompt. Replace the <placeholders> before running them:")
+	for _, m := range matches {
+		fmt.Fprintf(w, "\n  %s (%s):\n      %s\n", m.Description, m.Page, m.Command)
+	}
+}
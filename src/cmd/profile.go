@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fl/profile"
+	"fl/utils"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// the --profile flag, found before the command line is parsed because the
+// profile's settings are the defaults of the other flags
+func ProfileArg(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--profile" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if value, ok := strings.CutPrefix(arg, "--profile="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func addProfileCommand(rootCmd *cobra.Command, flags *FlagConfig) {
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage configuration profiles",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:           "list",
+		Aliases:       []string{"ls"},
+		Short:         "List profiles, the current one is marked with *",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := flags.Profiles.List()
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				marker := " "
+				if name == flags.Profiles.Current() {
+					marker = "*"
+				}
+				if name != flags.Profiles.Current() && name == flags.Profile {
+					fmt.Printf("%s %s (selected with --profile or $%s)\n", marker, name, profile.Env)
+				} else {
+					fmt.Printf("%s %s\n", marker, name)
+				}
+			}
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	useCmd := &cobra.Command{
+		Use:           "use <name>",
+		Short:         "Make a profile the current one",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := flags.Profiles.Use(args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Using profile '%s'.\n", args[0])
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	createCmd := &cobra.Command{
+		Use:           "create <name>",
+		Short:         "Create a profile",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, _ := cmd.Flags().GetString("from")
+			if err := flags.Profiles.Create(args[0], from); err != nil {
+				return err
+			}
+
+			if use, _ := cmd.Flags().GetBool("use"); use {
+				if err := flags.Profiles.Use(args[0]); err != nil {
+					return err
+				}
+			}
+
+			fmt.Printf("Created profile '%s', log in with: fl --profile %s subscription login\n", args[0], args[0])
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:           "delete <name>",
+		Aliases:       []string{"rm"},
+		Short:         "Delete a profile and its login",
+		Args:          cobra.ExactArgs(1),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			if !flags.Profiles.Exists(name) {
+				return fmt.Errorf("profile '%s' does not exist", name)
+			}
+			if force, _ := cmd.Flags().GetBool("force"); !force && !utils.PromptYesNo(fmt.Sprintf("Delete profile '%s'?", name)) {
+				return nil
+			}
+			if err := flags.Profiles.Delete(name); err != nil {
+				return err
+			}
+
+			// the login of the profile goes with it
+			if store, err := openCredentialStore(flags); err == nil {
+				if err := store.Delete(profile.FLIDKey(name)); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: cannot delete the FLID of profile '%s' from the %s credential store: %v\n", name, store.Name(), err)
+				}
+			}
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			os.Exit(0)
+		},
+	}
+
+	profileCmd.AddCommand(listCmd)
+
+	profileCmd.AddCommand(useCmd)
+
+	profileCmd.AddCommand(createCmd)
+	createCmd.PersistentFlags().String("from", "", "Copy the settings of another profile")
+	createCmd.PersistentFlags().Bool("use", false, "Make the new profile the current one")
+
+	profileCmd.AddCommand(deleteCmd)
+	deleteCmd.PersistentFlags().BoolP("force", "f", false, "Do not ask for confirmation")
+
+	rootCmd.AddCommand(profileCmd)
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/spf13/cobra"
 )
 
-func addSubscribeCommand(rootCmd *cobra.Command, filepath string, flags *FlagConfig) {
+func addSubscribeCommand(rootCmd *cobra.Command, flags *FlagConfig) {
 	subscribeCmd := &cobra.Command{
 		Use:           "subscription",
 		Aliases:       []string{"sub"},
@@ -30,29 +30,32 @@ func addSubscribeCommand(rootCmd *cobra.Command, filepath string, flags *FlagCon
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// a FLID still in the config file is moved before it is replaced
+			LoadFLID(flags)
+
 			flid := ""
 			err := error(nil)
 
 			if guest, _ := cmd.Flags().GetBool("guest"); guest {
-				flid, err = loginGuest()
+				flid, err = loginGuest(cmd.Context())
 			} else {
-				flid, err = loginGitHub(flags.Verbose, api.GitHubClientID)
+				flid, err = loginGitHub(cmd.Context(), flags.Verbose, api.GitHubClientID)
 			}
 
 			if err != nil {
 				return err
 			}
 
-			// save the flid to the configuration file for future use
+			// save the flid to the credential store for future use
 			flags.FLID = flid
 
-			err = writeConfig(filepath, *flags)
+			err = writeFLID(*flags)
 			if err != nil {
 				return err
 			}
 
 			if subscribe, _ := cmd.Flags().GetBool("subscribe"); subscribe {
-				return startSubscription(flags)
+				return startSubscription(cmd.Context(), flags)
 			}
 
 			return nil
@@ -69,7 +72,7 @@ func addSubscribeCommand(rootCmd *cobra.Command, filepath string, flags *FlagCon
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return startSubscription(flags)
+			return startSubscription(cmd.Context(), flags)
 		},
 		PostRun: func(cmd *cobra.Command, args []string) {
 			os.Exit(0)
@@ -83,7 +86,7 @@ func addSubscribeCommand(rootCmd *cobra.Command, filepath string, flags *FlagCon
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return cancelSubscription(flags)
+			return cancelSubscription(cmd.Context(), flags)
 		},
 		PostRun: func(cmd *cobra.Command, args []string) {
 			os.Exit(0)
@@ -97,7 +100,7 @@ func addSubscribeCommand(rootCmd *cobra.Command, filepath string, flags *FlagCon
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return statusSubscription(flags)
+			return statusSubscription(cmd.Context(), flags)
 		},
 		PostRun: func(cmd *cobra.Command, args []string) {
 			os.Exit(0)
@@ -1,18 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"fl/api"
 	"fl/utils"
 	"fmt"
 	"time"
 )
 
-func startSubscription(flags *FlagConfig) error {
+func startSubscription(ctx context.Context, flags *FlagConfig) error {
+	LoadFLID(flags)
 	if flags.FLID == "" {
 		return LoginMessage(false)
 	}
 
-	status, err := api.StartSubscription(flags.FLID)
+	status, err := api.StartSubscription(ctx, flags.FLID)
 	if err != nil {
 		return err
 	}
@@ -29,12 +31,13 @@ func startSubscription(flags *FlagConfig) error {
 	return nil
 }
 
-func cancelSubscription(flags *FlagConfig) error {
+func cancelSubscription(ctx context.Context, flags *FlagConfig) error {
+	LoadFLID(flags)
 	if flags.FLID == "" {
 		return LoginMessage(false)
 	}
 
-	status, err := api.CancelSubscription(flags.FLID)
+	status, err := api.CancelSubscription(ctx, flags.FLID)
 	if err != nil {
 		return err
 	}
@@ -43,12 +46,13 @@ func cancelSubscription(flags *FlagConfig) error {
 	return nil
 }
 
-func statusSubscription(flags *FlagConfig) error {
+func statusSubscription(ctx context.Context, flags *FlagConfig) error {
+	LoadFLID(flags)
 	if flags.FLID == "" {
 		return LoginMessage(false)
 	}
 
-	status, err := api.StatusOfSubscription(flags.FLID)
+	status, err := api.StatusOfSubscription(ctx, flags.FLID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// test values come from the default, then the file, then the environment
+func TestResolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.json")
+	os.WriteFile(path, []byte(`{"run": true, "generator": {"backend": "openai", "maxtokens": 100}}`), 0600)
+	t.Setenv("FL_GENERATOR_MAXTOKENS", "200")
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	s, err := Resolve(f)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	expected := map[string]Value{
+		"langtool":              {Value: "", Source: FromDefault},
+		"run":                   {Value: true, Source: FromFile},
+		"generator.backend":     {Value: "openai", Source: FromFile},
+		"generator.maxtokens":   {Value: 200, Source: FromEnv},
+		"generator.temperature": {Value: 0.0, Source: FromDefault},
+	}
+	for name, e := range expected {
+		if v := s.Get(name); v.Value != e.Value || v.Source != e.Source {
+			t.Fatalf(`Get("%s") = %v from %s, expected %v from %s`, name, v.Value, v.Source, e.Value, e.Source)
+		}
+	}
+
+	t.Setenv("FL_RUN", "maybe")
+	if _, err := Resolve(f); err == nil || !strings.Contains(err.Error(), "FL_RUN") {
+		t.Fatalf("Resolve with FL_RUN=maybe = %v, expected an error naming FL_RUN", err)
+	}
+}
+
+// test objects are overridden with JSON and only have known entries
+func TestObjects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.json")
+	os.WriteFile(path, []byte(`{"api": {"paths": {"generate": "/gen"}}}`), 0600)
+	f, _ := Load(path)
+
+	t.Setenv("FL_REDACT_PATTERNS", `{"ticket": "T-[0-9]+"}`)
+	s, err := Resolve(f)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if v := s.Get("redact.patterns"); v.Source != FromEnv || s.StringMap("redact.patterns")["ticket"] != "T-[0-9]+" {
+		t.Fatalf(`Get("redact.patterns") = %v from %s, expected the pattern from FL_REDACT_PATTERNS`, v.Value, v.Source)
+	}
+	if v := s.Get("api.paths"); v.Source != FromFile || s.StringMap("api.paths")["generate"] != "/gen" {
+		t.Fatalf(`Get("api.paths") = %v from %s, expected the path from the file`, v.Value, v.Source)
+	}
+
+	t.Setenv("FL_API_PATHS", `{"generate": "/gen", "genrate": "/typo"}`)
+	if _, err := Resolve(f); err == nil || !strings.Contains(err.Error(), "FL_API_PATHS") || !strings.Contains(err.Error(), "genrate") {
+		t.Fatalf("Resolve with an unknown endpoint in FL_API_PATHS = %v, expected an error naming it", err)
+	}
+	t.Setenv("FL_API_PATHS", `/gen`)
+	if _, err := Resolve(f); err == nil {
+		t.Fatalf("Resolve with FL_API_PATHS=/gen succeeded, expected a JSON error")
+	}
+
+	k, entry, _ := Lookup("api.paths.genrate")
+	if err := k.CheckEntry(entry); err == nil {
+		t.Fatalf(`CheckEntry("genrate") of api.paths succeeded, expected an unknown entry error`)
+	}
+	k, entry, _ = Lookup("redact.patterns.anything")
+	if err := k.CheckEntry(entry); err != nil {
+		t.Fatalf(`CheckEntry("anything") of redact.patterns = %v, expected any name to be allowed`, err)
+	}
+}
+
+// test keys are set and unset without touching the others
+func TestSetUnset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.json")
+	os.WriteFile(path, []byte(`{"run": true, "unknown": 1}`), 0600)
+	f, _ := Load(path)
+
+	f.Set("api.paths.generate", "/gen")
+	f.Set("langtool", "zsh")
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	f, _ = Load(path)
+	if v, _ := f.Get("run"); v != true {
+		t.Fatalf(`Get("run") = %v after setting other keys, expected true`, v)
+	}
+	if v, _ := f.Get("api.paths.generate"); v != "/gen" {
+		t.Fatalf(`Get("api.paths.generate") = %v, expected "/gen"`, v)
+	}
+
+	if !f.Unset("api.paths.generate") {
+		t.Fatalf(`Unset("api.paths.generate") = false, expected true`)
+	}
+	if _, ok := f.Get("api"); ok {
+		t.Fatalf(`Get("api") after unsetting its only entry succeeded, expected the empty object to be removed`)
+	}
+
+	if err := f.Validate(); err == nil || !strings.Contains(err.Error(), "unknown key 'unknown'") {
+		t.Fatalf("Validate = %v, expected the unknown key to be reported", err)
+	}
+	if err := f.Replace([]byte(`{"run": "yes"}`)); err == nil {
+		t.Fatalf(`Replace with run "yes" succeeded, expected a type error`)
+	}
+}
+
+// test values given as text are checked against the schema
+func TestParse(t *testing.T) {
+	k, _, _ := Lookup("generator.backend")
+	if v, err := k.Parse("OpenAI"); v != "openai" || err != nil {
+		t.Fatalf(`Parse("OpenAI") = (%v, %v), expected "openai"`, v, err)
+	}
+	if _, err := k.Parse("gpt"); err == nil {
+		t.Fatalf(`Parse("gpt") succeeded, expected an error`)
+	}
+
+	k, _, _ = Lookup("langtool")
+	if v, err := k.Parse("sh"); err != nil || v == "" {
+		t.Fatalf(`Parse("sh") = (%v, %v), expected a langtool`, v, err)
+	}
+
+	if _, _, err := Lookup("nope"); err == nil {
+		t.Fatalf(`Lookup("nope") succeeded, expected an error`)
+	}
+	if k, entry, err := Lookup("redact.patterns.ticket"); k.Name != "redact.patterns" || entry != "ticket" || err != nil {
+		t.Fatalf(`Lookup("redact.patterns.ticket") = (%s, %s, %v), expected an entry of redact.patterns`, k.Name, entry, err)
+	}
+}
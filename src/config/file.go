@@ -0,0 +1,170 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fl/profile"
+	"fl/utils"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// a profile's config file, nested JSON objects along the dotted key names
+type File struct {
+	path   string
+	values map[string]interface{}
+}
+
+// the config file at path, empty if it does not exist yet
+func Load(path string) (*File, error) {
+	f := &File{path: path, values: map[string]interface{}{}}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		return f, nil
+	}
+	if err := json.Unmarshal(raw, &f.values); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return f, nil
+}
+
+func (f *File) Path() string {
+	return f.path
+}
+
+// the value at a dotted name, which need not be a key of the schema
+func (f *File) Get(name string) (interface{}, bool) {
+	parent, last := f.parent(name, false)
+	if parent == nil {
+		return nil, false
+	}
+	v, ok := parent[last]
+	return v, ok
+}
+
+func (f *File) Set(name string, value interface{}) {
+	parent, last := f.parent(name, true)
+	parent[last] = value
+}
+
+// remove a value and the objects it leaves empty, false if it was not set
+func (f *File) Unset(name string) bool {
+	parent, last := f.parent(name, false)
+	if parent == nil {
+		return false
+	}
+	if _, ok := parent[last]; !ok {
+		return false
+	}
+	delete(parent, last)
+
+	// prune empty objects from the innermost out
+	parts := strings.Split(name, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		prefix := strings.Join(parts[:i], ".")
+		if v, ok := f.Get(prefix); ok {
+			if m, ok := v.(map[string]interface{}); ok && len(m) == 0 {
+				p, l := f.parent(prefix, false)
+				delete(p, l)
+				continue
+			}
+		}
+		break
+	}
+	return true
+}
+
+// the object holding the last part of a name, created along the way if create is set
+func (f *File) parent(name string, create bool) (map[string]interface{}, string) {
+	parts := strings.Split(name, ".")
+	m := f.values
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[part].(map[string]interface{})
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+			next = map[string]interface{}{}
+			m[part] = next
+		}
+		m = next
+	}
+	return m, parts[len(parts)-1]
+}
+
+// check every value in the file against the schema, unknown keys included
+func (f *File) Validate() error {
+	var problems []string
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for name, v := range m {
+			full := prefix + name
+			if full == "version" || full == "flid" {
+				continue
+			}
+			if k, entry, err := Lookup(full); err == nil && entry == "" {
+				if _, err := k.Check(v); err != nil {
+					problems = append(problems, err.Error())
+				}
+			} else if nested, ok := v.(map[string]interface{}); ok && isPrefix(full) {
+				walk(full+".", nested)
+			} else {
+				problems = append(problems, fmt.Sprintf("unknown key '%s'", full))
+			}
+		}
+	}
+	walk("", f.values)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config %s: %s", f.path, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// whether some key is nested under the name
+func isPrefix(name string) bool {
+	for _, k := range keys {
+		if strings.HasPrefix(k.Name, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// the file as it is saved, with the schema version
+func (f *File) Marshal() ([]byte, error) {
+	f.values["version"] = profile.Version
+	return json.MarshalIndent(f.values, "", "  ")
+}
+
+// the config file may hold API keys, it is only readable by the user
+func (f *File) Save() error {
+	raw, err := f.Marshal()
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(f.path, raw, 0600)
+}
+
+// replace the contents with edited JSON, kept only if it is valid
+func (f *File) Replace(raw []byte) error {
+	edited := &File{path: f.path, values: map[string]interface{}{}}
+	if len(strings.TrimSpace(string(raw))) > 0 {
+		if err := json.Unmarshal(raw, &edited.values); err != nil {
+			return fmt.Errorf("invalid JSON: %v", err)
+		}
+	}
+	if err := edited.Validate(); err != nil {
+		return err
+	}
+	f.values = edited.values
+	return nil
+}
@@ -0,0 +1,274 @@
+package config
+
+import (
+	"encoding/json"
+	"fl/api"
+	"fl/credentials"
+	"fl/environment"
+	"fl/langtool"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// value types, named as in JSON Schema
+const (
+	Boolean = "boolean"
+	String  = "string"
+	Integer = "integer"
+	Number  = "number"
+	Object  = "object" // a map of strings, e.g. the API paths or redaction patterns
+)
+
+// a setting in a profile's config file
+type Key struct {
+	Name        string      // dotted path in the file, e.g. generator.backend
+	Type        string      // one of the types above
+	Default     interface{} // the value when it is set nowhere
+	Enum        []string    // the allowed values of a string
+	Description string
+	Env         string   // the environment variable that overrides the key, objects are given as JSON
+	Secret      bool     // masked when listed
+	Entries     []string // the entry names an object may have, any if nil
+
+	normalize func(string) (string, error) // canonical form of a string value
+}
+
+var keys = []Key{
+	{Name: "run", Type: Boolean, Default: false, Description: "Execute generated commands without asking"},
+	{Name: "langtool", Type: String, Default: "", Description: "Default shell or tool commands are generated for (see fl langtools)", normalize: langtoolName},
+	{Name: "credentials.store", Type: String, Default: credentials.Auto, Enum: credentials.Backends(), Env: credentials.StoreEnv, Description: "Where the FLID is kept"},
+
+	{Name: "generator.backend", Type: String, Default: api.BackendFlows, Enum: api.Backends(), Description: "Backend that generates commands"},
+	{Name: "generator.model", Type: String, Default: "", Description: "Model used by the openai and local backends, empty for the backend's default"},
+	{Name: "generator.url", Type: String, Default: "", Description: "Endpoint of the openai and local backends, empty for the backend's default"},
+	{Name: "generator.apikey", Type: String, Default: "", Secret: true, Description: "API key of the openai backend, defaults to $OPENAI_API_KEY"},
+	{Name: "generator.temperature", Type: Number, Default: 0.0, Description: "Sampling temperature"},
+	{Name: "generator.maxtokens", Type: Integer, Default: 512, Description: "Upper bound on generated tokens"},
+
+	{Name: "redact.disabled", Type: Boolean, Default: false, Description: "Send prompts without redacting secrets"},
+	{Name: "redact.emails", Type: Boolean, Default: false, Description: "Also redact email addresses"},
+	{Name: "redact.hostnames", Type: Boolean, Default: false, Description: "Also redact host names"},
+	{Name: "redact.patterns", Type: Object, Default: map[string]string{}, Description: "Custom redaction rules, rule name to regular expression"},
+
+	{Name: "api.baseurl", Type: String, Default: api.DefaultBaseURL, Env: "FL_API_URL", Description: "Scheme and host of the Flows deployment"},
+	{Name: "api.paths", Type: Object, Default: map[string]string{}, Entries: api.EndpointNames(), Description: "Endpoint paths, overridden one at a time by FL_API_<ENDPOINT>"},
+
+	{Name: "http.timeout", Type: Integer, Default: 60, Description: "Seconds an API request may take"},
+	{Name: "http.retries", Type: Integer, Default: 3, Description: "Retries of failed API requests that are safe to repeat, 0 disables them"},
+	{Name: "http.proxy", Type: String, Default: "", Description: "Proxy URL for API requests, empty uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY"},
+	{Name: "http.cafile", Type: String, Default: "", Description: "PEM bundle of CAs trusted in addition to the system roots, e.g. of a TLS-inspecting proxy"},
+	{Name: "http.certfile", Type: String, Default: "", Description: "Client certificate (PEM) for mutual TLS"},
+	{Name: "http.keyfile", Type: String, Default: "", Description: "Key (PEM) of the client certificate"},
+
+	{Name: "history.maxentries", Type: Integer, Default: 1000, Description: "Number of history entries kept, negative keeps all"},
+	{Name: "history.maxdays", Type: Integer, Default: 90, Description: "Days history entries are kept, negative keeps them forever"},
+
+	{Name: "cache.ttlhours", Type: Integer, Default: 168, Description: "Hours a cached command is reused"},
+	{Name: "cache.maxmb", Type: Integer, Default: 10, Description: "Size limit of the response cache in MB"},
+}
+
+func init() {
+	for _, c := range environment.Categories() {
+		keys = append(keys, Key{Name: "context." + c, Type: Boolean, Default: false, Description: "Send the " + c + " environment context with prompts"})
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+
+	for i := range keys {
+		if keys[i].Env == "" {
+			keys[i].Env = "FL_" + strings.ToUpper(strings.ReplaceAll(keys[i].Name, ".", "_"))
+		}
+	}
+}
+
+func langtoolName(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	lt, err := langtool.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	return lt.Name, nil
+}
+
+// all keys in alphabetical order
+func Keys() []Key {
+	return keys
+}
+
+// the key of a name, an entry of an object key such as api.paths.generate
+// returns the object key and the entry name
+func Lookup(name string) (Key, string, error) {
+	for _, k := range keys {
+		if k.Name == name {
+			return k, "", nil
+		}
+		if entry, ok := strings.CutPrefix(name, k.Name+"."); ok && k.Type == Object && entry != "" {
+			return k, entry, nil
+		}
+	}
+	return Key{}, "", fmt.Errorf("unknown key '%s' (see fl config list)", name)
+}
+
+// the value of a key given on the command line or in the environment,
+// objects are given as JSON
+func (k Key) Parse(text string) (interface{}, error) {
+	switch k.Type {
+	case Boolean:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", k.Name)
+		}
+		return b, nil
+	case Integer:
+		i, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer", k.Name)
+		}
+		return i, nil
+	case Number:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", k.Name)
+		}
+		return f, nil
+	case Object:
+		var v interface{}
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			return nil, fmt.Errorf("%s must be a JSON object", k.Name)
+		}
+		return k.Check(v)
+	}
+	return k.Check(text)
+}
+
+// the value in its canonical type if it is valid for the key, values read
+// from JSON are float64 numbers and generic maps
+func (k Key) Check(v interface{}) (interface{}, error) {
+	switch k.Type {
+	case Boolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("%s must be true or false", k.Name)
+
+	case Integer:
+		switch n := v.(type) {
+		case int:
+			return n, nil
+		case float64:
+			if n == math.Trunc(n) {
+				return int(n), nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be an integer", k.Name)
+
+	case Number:
+		switch n := v.(type) {
+		case int:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+		return nil, fmt.Errorf("%s must be a number", k.Name)
+
+	case Object:
+		entries := map[string]string{}
+		switch m := v.(type) {
+		case map[string]string:
+			entries = m
+		case map[string]interface{}:
+			for name, value := range m {
+				s, ok := value.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s.%s must be a string", k.Name, name)
+				}
+				entries[name] = s
+			}
+		default:
+			return nil, fmt.Errorf("%s must be an object of strings", k.Name)
+		}
+		for name := range entries {
+			if err := k.CheckEntry(name); err != nil {
+				return nil, err
+			}
+		}
+		return entries, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string", k.Name)
+	}
+	if k.normalize != nil {
+		var err error
+		if s, err = k.normalize(s); err != nil {
+			return nil, fmt.Errorf("%s: %v", k.Name, err)
+		}
+	}
+	// an empty string leaves the choice to the default
+	if len(k.Enum) > 0 && s != "" {
+		s = strings.ToLower(s)
+		for _, e := range k.Enum {
+			if s == e {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be one of %s", k.Name, strings.Join(k.Enum, ", "))
+	}
+	return s, nil
+}
+
+// whether an object may have an entry of that name
+func (k Key) CheckEntry(name string) error {
+	if k.Entries == nil {
+		return nil
+	}
+	for _, e := range k.Entries {
+		if name == e {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown entry '%s' of %s (one of %s)", name, k.Name, strings.Join(k.Entries, ", "))
+}
+
+// the JSON Schema of a profile's config file
+func Schema() []byte {
+	root := map[string]interface{}{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      "fl profile configuration",
+		"type":       "object",
+		"properties": map[string]interface{}{"version": map[string]interface{}{"type": "integer"}},
+	}
+
+	for _, k := range keys {
+		property := map[string]interface{}{"type": k.Type, "description": k.Description, "default": k.Default}
+		if len(k.Enum) > 0 {
+			property["enum"] = k.Enum
+		}
+		if k.Type == Object {
+			property["additionalProperties"] = map[string]interface{}{"type": String}
+			if k.Entries != nil {
+				property["propertyNames"] = map[string]interface{}{"enum": k.Entries}
+			}
+		}
+
+		// nested objects down to the key
+		parent := root
+		parts := strings.Split(k.Name, ".")
+		for _, part := range parts[:len(parts)-1] {
+			properties := parent["properties"].(map[string]interface{})
+			if _, ok := properties[part]; !ok {
+				properties[part] = map[string]interface{}{"type": Object, "properties": map[string]interface{}{}, "additionalProperties": false}
+			}
+			parent = properties[part].(map[string]interface{})
+		}
+		parent["properties"].(map[string]interface{})[parts[len(parts)-1]] = property
+	}
+
+	out, _ := json.MarshalIndent(root, "", "  ")
+	return out
+}
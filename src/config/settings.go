@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// where a value comes from, each source overrides the ones before it
+const (
+	FromDefault = "default"
+	FromFile    = "file"
+	FromEnv     = "env"
+	FromFlag    = "flag"
+)
+
+type Value struct {
+	Key    Key
+	Value  interface{}
+	Source string
+}
+
+// the value of every key after defaults, the config file and the environment
+type Settings struct {
+	values map[string]*Value
+}
+
+func Resolve(f *File) (*Settings, error) {
+	s := &Settings{values: map[string]*Value{}}
+
+	for _, k := range keys {
+		v := &Value{Key: k, Value: k.Default, Source: FromDefault}
+		s.values[k.Name] = v
+
+		if raw, ok := f.Get(k.Name); ok {
+			value, err := k.Check(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid config %s: %v", f.Path(), err)
+			}
+			v.Value, v.Source = value, FromFile
+		}
+
+		if k.Env == "" {
+			continue
+		}
+		if text, ok := os.LookupEnv(k.Env); ok && text != "" {
+			value, err := k.Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", k.Env, err)
+			}
+			v.Value, v.Source = value, FromEnv
+		}
+	}
+	return s, nil
+}
+
+// replace a value, e.g. with a command-line flag
+func (s *Settings) Override(name string, value interface{}, source string) {
+	if v, ok := s.values[name]; ok {
+		v.Value, v.Source = value, source
+	}
+}
+
+func (s *Settings) Get(name string) Value {
+	if v, ok := s.values[name]; ok {
+		return *v
+	}
+	return Value{}
+}
+
+// all values in key order
+func (s *Settings) Values() []Value {
+	values := make([]Value, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, *s.values[k.Name])
+	}
+	return values
+}
+
+func (s *Settings) Bool(name string) bool {
+	b, _ := s.Get(name).Value.(bool)
+	return b
+}
+
+func (s *Settings) String(name string) string {
+	str, _ := s.Get(name).Value.(string)
+	return str
+}
+
+func (s *Settings) Int(name string) int {
+	i, _ := s.Get(name).Value.(int)
+	return i
+}
+
+func (s *Settings) Float(name string) float64 {
+	f, _ := s.Get(name).Value.(float64)
+	return f
+}
+
+// a copy the caller may change
+func (s *Settings) StringMap(name string) map[string]string {
+	m, _ := s.Get(name).Value.(map[string]string)
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,69 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// credential store backends
+const (
+	Auto    = "auto"    // the keyring if it is available, else the encrypted file
+	Keyring = "keyring" // the Secret Service over D-Bus, the macOS keychain or the Windows credential manager
+	File    = "file"    // a passphrase-encrypted file readable only by the user
+)
+
+// FL_CREDENTIAL_STORE overrides the configured backend and FL_PASSPHRASE
+// unlocks the encrypted file without a prompt
+const (
+	StoreEnv      = "FL_CREDENTIAL_STORE"
+	PassphraseEnv = "FL_PASSPHRASE"
+)
+
+// returned by Get when nothing is stored under the key
+var ErrNotFound = errors.New("credential not found")
+
+// secrets such as the FLID, kept out of the config file
+type Store interface {
+	Name() string
+	Get(key string) (string, error)
+	Set(key string, value string) error
+	Delete(key string) error
+}
+
+func Backends() []string {
+	return []string{Auto, Keyring, File}
+}
+
+// open the configured store, the encrypted file is kept at path
+func Open(backend string, path string) (Store, error) {
+	if env := os.Getenv(StoreEnv); env != "" {
+		backend = env
+	}
+
+	switch strings.ToLower(backend) {
+	case "", Auto:
+		if keyringAvailable() {
+			return keyringStore{}, nil
+		}
+		return NewFileStore(path, TerminalPassphrase), nil
+	case Keyring:
+		if !keyringAvailable() {
+			return nil, fmt.Errorf("no keyring is available, use the %s credential store", File)
+		}
+		return keyringStore{}, nil
+	case File:
+		return NewFileStore(path, TerminalPassphrase), nil
+	}
+
+	return nil, fmt.Errorf("unsupported credential store '%s' (supported: %s)", backend, strings.Join(Backends(), ", "))
+}
+
+// a masked form of a secret that is enough to recognize it
+func Mask(secret string) string {
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", len(secret)-8) + secret[len(secret)-4:]
+}
@@ -0,0 +1,62 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func passphrase(p string) PassphraseFunc {
+	return func(create bool) (string, error) {
+		return p, nil
+	}
+}
+
+// test credentials are encrypted in a file only the user can read
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".flcredentials")
+	store := NewFileStore(path, passphrase("secret"))
+
+	if _, err := store.Get("flid"); err != ErrNotFound {
+		t.Fatalf(`Get("flid") on an empty store = %v, expected ErrNotFound`, err)
+	}
+	if err := store.Set("flid", "my-flid"); err != nil {
+		t.Fatalf(`Set("flid") failed: %v`, err)
+	}
+
+	raw, _ := os.ReadFile(path)
+	info, _ := os.Stat(path)
+	if strings.Contains(string(raw), "my-flid") || info.Mode().Perm() != 0600 {
+		t.Fatalf("store file = %q with mode %v, expected encrypted with mode 0600", raw, info.Mode().Perm())
+	}
+
+	// a looser mode is tightened when the file is read
+	os.Chmod(path, 0644)
+	value, err := NewFileStore(path, passphrase("secret")).Get("flid")
+	info, _ = os.Stat(path)
+	if value != "my-flid" || err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf(`Get("flid") = ("%s", %v) with mode %v, expected ("my-flid", nil) with mode 0600`, value, err, info.Mode().Perm())
+	}
+
+	if _, err := NewFileStore(path, passphrase("wrong")).Get("flid"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Fatalf(`Get("flid") with the wrong passphrase = %v, expected an error`, err)
+	}
+
+	if err := store.Delete("flid"); err != nil {
+		t.Fatalf(`Delete("flid") failed: %v`, err)
+	}
+	if _, err := store.Get("flid"); err != ErrNotFound {
+		t.Fatalf(`Get("flid") after Delete = %v, expected ErrNotFound`, err)
+	}
+}
+
+// test secrets are masked except for their ends
+func TestMask(t *testing.T) {
+	if m := Mask("abcdefghijkl"); m != "abcd****ijkl" {
+		t.Fatalf(`Mask("abcdefghijkl") = "%s", expected "abcd****ijkl"`, m)
+	}
+	if m := Mask("short"); m != "*****" {
+		t.Fatalf(`Mask("short") = "%s", expected "*****"`, m)
+	}
+}
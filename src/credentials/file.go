@@ -0,0 +1,225 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fl/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// asks for the passphrase of the encrypted file, create is set when the file
+// is about to be created so the passphrase can be confirmed
+type PassphraseFunc func(create bool) (string, error)
+
+// the passphrase from $FL_PASSPHRASE, else asked for on the terminal
+func TerminalPassphrase(create bool) (string, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	passphrase, err := utils.PromptPassword("Passphrase for the fl credential store: ")
+	if err != nil {
+		return "", fmt.Errorf("the credential store is locked, set %s or use a keyring: %v", PassphraseEnv, err)
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("the passphrase cannot be empty")
+	}
+
+	if create {
+		confirm, err := utils.PromptPassword("Repeat the passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if confirm != passphrase {
+			return "", fmt.Errorf("the passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// the file holds the credentials as JSON sealed with AES-GCM under a key
+// derived from the passphrase with scrypt
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+const fileVersion = 1
+
+type fileStore struct {
+	path       string
+	passphrase PassphraseFunc
+	unlocked   string // the passphrase once it was asked for
+}
+
+func NewFileStore(path string, passphrase PassphraseFunc) Store {
+	return &fileStore{path: path, passphrase: passphrase}
+}
+
+func (s *fileStore) Name() string {
+	return File
+}
+
+func (s *fileStore) Get(key string) (string, error) {
+	values, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *fileStore) Set(key string, value string) error {
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+	values[key] = value
+	return s.save(values)
+}
+
+func (s *fileStore) Delete(key string) error {
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := values[key]; !ok {
+		return nil
+	}
+	delete(values, key)
+	return s.save(values)
+}
+
+func (s *fileStore) unlock(create bool) (string, error) {
+	if s.unlocked == "" {
+		passphrase, err := s.passphrase(create)
+		if err != nil {
+			return "", err
+		}
+		s.unlocked = passphrase
+	}
+	return s.unlocked, nil
+}
+
+// the stored credentials, none if the file does not exist yet
+func (s *fileStore) load() (map[string]string, error) {
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// the file is only ever readable by the user
+	if info, err := os.Stat(s.path); err == nil && info.Mode().Perm()&0077 != 0 {
+		if err := os.Chmod(s.path, 0600); err != nil {
+			return nil, err
+		}
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("invalid credential store %s: %v", s.path, err)
+	}
+	if file.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported credential store version %d in %s", file.Version, s.path)
+	}
+
+	passphrase, err := s.unlock(false)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(passphrase, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		s.unlocked = ""
+		return nil, fmt.Errorf("wrong passphrase for the credential store %s", s.path)
+	}
+
+	values := map[string]string{}
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("invalid credential store %s: %v", s.path, err)
+	}
+	return values, nil
+}
+
+// seal the credentials with a fresh salt and nonce and replace the file atomically
+func (s *fileStore) save(values map[string]string) error {
+	_, statErr := os.Stat(s.path)
+	passphrase, err := s.unlock(errors.Is(statErr, os.ErrNotExist))
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	file := encryptedFile{Version: fileVersion, Salt: make([]byte, 16)}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+	gcm, err := newGCM(passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Data = gcm.Seal(nil, file.Nonce, plain, nil)
+
+	raw, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".flcredentials-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp already uses 0600, chmod in case of an unusual umask or platform
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	_, err = tmp.Write(raw)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,40 @@
+package credentials
+
+import (
+	"github.com/zalando/go-keyring"
+)
+
+// the service name entries are stored under in the keyring
+const keyringService = "fl"
+
+type keyringStore struct{}
+
+// whether a keyring answers, a missing entry still means it works
+func keyringAvailable() bool {
+	_, err := keyring.Get(keyringService, "probe")
+	return err == nil || err == keyring.ErrNotFound
+}
+
+func (keyringStore) Name() string {
+	return Keyring
+}
+
+func (keyringStore) Get(key string) (string, error) {
+	value, err := keyring.Get(keyringService, key)
+	if err == keyring.ErrNotFound {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (keyringStore) Set(key string, value string) error {
+	return keyring.Set(keyringService, key, value)
+}
+
+func (keyringStore) Delete(key string) error {
+	err := keyring.Delete(keyringService, key)
+	if err == keyring.ErrNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,355 @@
+package environment
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// categories of context, each is sent only when enabled in the config
+const (
+	OS        = "os"        // operating system and distribution
+	Coreutils = "coreutils" // GNU, BSD or BusyBox flavor of the standard tools
+	Shell     = "shell"     // the user's login shell
+	Tools     = "tools"     // which relevant binaries are installed
+	Files     = "files"     // working directory layout
+	Versions  = "versions"  // versions of the installed tools
+)
+
+const (
+	MaxFileDepth   = 2  // directory levels listed below the working directory
+	MaxFileEntries = 50 // entries listed before the layout is cut off
+
+	probeTimeout = 2 * time.Second
+)
+
+// names of all categories in the order they are shown
+func Categories() []string {
+	return []string{OS, Coreutils, Shell, Tools, Files, Versions}
+}
+
+// binaries whose presence changes which command is best
+var tools = []string{
+	"rg", "fd", "fdfind", "jq", "yq", "gawk", "mawk", "gsed", "gfind", "xargs", "parallel",
+	"fzf", "bat", "git", "docker", "kubectl", "python3", "perl", "node", "curl", "wget",
+}
+
+// tools whose flavor decides which flags are available
+var coreutils = []string{"ls", "sed", "find", "grep", "date"}
+
+// tools whose version is reported, when installed
+var versioned = []string{"bash", "zsh", "fish", "git", "python3", "node", "jq", "rg", "fd", "gawk", "docker", "kubectl"}
+
+// which categories are enabled, all are off unless opted in
+type Toggles map[string]bool
+
+// parse a comma separated list of categories, "none" disables all of them
+func ParseToggles(list string) (Toggles, error) {
+	toggles := Toggles{}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "" || name == "none":
+		case name == "all":
+			for _, c := range Categories() {
+				toggles[c] = true
+			}
+		case valid(name):
+			toggles[name] = true
+		default:
+			return nil, fmt.Errorf("unknown context category '%s' (supported: %s)", name, strings.Join(Categories(), ", "))
+		}
+	}
+	return toggles, nil
+}
+
+func valid(name string) bool {
+	for _, c := range Categories() {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// the enabled categories, comma separated
+func (t Toggles) String() string {
+	enabled := []string{}
+	for _, c := range Categories() {
+		if t[c] {
+			enabled = append(enabled, c)
+		}
+	}
+	if len(enabled) == 0 {
+		return "none"
+	}
+	return strings.Join(enabled, ",")
+}
+
+func (t Toggles) Any() bool {
+	for _, c := range Categories() {
+		if t[c] {
+			return true
+		}
+	}
+	return false
+}
+
+// facts about the machine the command will run on
+type Context struct {
+	OS        string
+	Coreutils string
+	Shell     string
+	Tools     []string
+	Files     []string
+	Versions  map[string]string
+}
+
+// collect the enabled categories, probes that fail are left out
+func Collect(toggles Toggles) *Context {
+	c := &Context{}
+	if toggles[OS] {
+		c.OS = osName()
+	}
+	if toggles[Coreutils] {
+		c.Coreutils = coreutilsFlavor()
+	}
+	if toggles[Shell] {
+		c.Shell = shellName()
+	}
+	if toggles[Tools] {
+		c.Tools = installed(tools)
+	}
+	if toggles[Files] {
+		c.Files = layout(MaxFileDepth, MaxFileEntries)
+	}
+	if toggles[Versions] {
+		c.Versions = versions(installed(versioned))
+	}
+	return c
+}
+
+// the context as text for the model, empty if nothing was collected
+func (c *Context) String() string {
+	var sb strings.Builder
+	if c.OS != "" {
+		fmt.Fprintf(&sb, "OS: %s\n", c.OS)
+	}
+	if c.Coreutils != "" {
+		fmt.Fprintf(&sb, "Coreutils: %s\n", c.Coreutils)
+	}
+	if c.Shell != "" {
+		fmt.Fprintf(&sb, "Shell: %s\n", c.Shell)
+	}
+	if len(c.Tools) > 0 {
+		fmt.Fprintf(&sb, "Installed tools: %s\n", strings.Join(c.Tools, ", "))
+	}
+	if len(c.Versions) > 0 {
+		names := make([]string, 0, len(c.Versions))
+		for name := range c.Versions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		sb.WriteString("Tool versions:\n")
+		for _, name := range names {
+			fmt.Fprintf(&sb, "  %s: %s\n", name, c.Versions[name])
+		}
+	}
+	if len(c.Files) > 0 {
+		sb.WriteString("Working directory:\n")
+		for _, f := range c.Files {
+			fmt.Fprintf(&sb, "  %s\n", f)
+		}
+	}
+	return sb.String()
+}
+
+// the distribution on linux, the product version on macOS
+func osName() string {
+	name := runtime.GOOS
+	switch runtime.GOOS {
+	case "linux":
+		if pretty := osRelease("/etc/os-release", "PRETTY_NAME"); pretty != "" {
+			name = "Linux (" + pretty + ")"
+		} else {
+			name = "Linux"
+		}
+	case "darwin":
+		if version := probe("sw_vers", "-productVersion"); version != "" {
+			name = "macOS " + version
+		} else {
+			name = "macOS"
+		}
+	}
+	return name + " " + runtime.GOARCH
+}
+
+func osRelease(path string, key string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), key+"="); ok {
+			return strings.Trim(value, `"'`)
+		}
+	}
+	return ""
+}
+
+// GNU, BSD or BusyBox, per tool if they are mixed (e.g., GNU sed installed on macOS)
+func coreutilsFlavor() string {
+	flavors := map[string]string{}
+	seen := map[string]bool{}
+	for _, tool := range coreutils {
+		if _, err := exec.LookPath(tool); err != nil {
+			continue
+		}
+		flavors[tool] = flavor(tool)
+		seen[flavors[tool]] = true
+	}
+
+	if len(seen) == 1 {
+		for f := range seen {
+			return f
+		}
+	}
+
+	mixed := []string{}
+	for _, tool := range coreutils {
+		if f, ok := flavors[tool]; ok {
+			mixed = append(mixed, tool+" "+f)
+		}
+	}
+	return strings.Join(mixed, ", ")
+}
+
+// BSD tools reject --version, GNU and BusyBox name themselves
+func flavor(tool string) string {
+	if path, err := exec.LookPath(tool); err == nil {
+		if target, err := filepath.EvalSymlinks(path); err == nil && filepath.Base(target) == "busybox" {
+			return "BusyBox"
+		}
+	}
+
+	// BusyBox sed claims to be "not GNU sed"
+	out := probe(tool, "--version")
+	switch {
+	case strings.Contains(out, "BusyBox"):
+		return "BusyBox"
+	case strings.Contains(out, "GNU") || strings.Contains(out, "Free Software Foundation"):
+		return "GNU"
+	}
+	return "BSD"
+}
+
+func shellName() string {
+	shell := os.Getenv("SHELL")
+	if shell == "" && runtime.GOOS == "windows" {
+		shell = os.Getenv("ComSpec")
+	}
+	if shell == "" {
+		return ""
+	}
+	return filepath.Base(shell)
+}
+
+func installed(names []string) []string {
+	found := []string{}
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err == nil {
+			found = append(found, name)
+		}
+	}
+	return found
+}
+
+// the first line of --version of each tool, probed in parallel
+func versions(names []string) map[string]string {
+	found := map[string]string{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if version := firstLine(probe(name, "--version")); version != "" {
+				mu.Lock()
+				found[name] = version
+				mu.Unlock()
+			}
+		}(name)
+	}
+
+	wg.Wait()
+	return found
+}
+
+// directories and files below the working directory, hidden entries are skipped
+func layout(depth int, limit int) []string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+
+	entries := []string{}
+	filepath.WalkDir(wd, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == wd {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if len(entries) == limit {
+			entries = append(entries, "...")
+			return filepath.SkipAll
+		}
+
+		rel, _ := filepath.Rel(wd, path)
+		if d.IsDir() {
+			entries = append(entries, rel+"/")
+			if strings.Count(rel, string(filepath.Separator))+1 >= depth {
+				return filepath.SkipDir
+			}
+		} else {
+			entries = append(entries, rel)
+		}
+		return nil
+	})
+	return entries
+}
+
+// output of a short command, empty if it fails or takes too long
+func probe(name string, args ...string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return strings.TrimSpace(line)
+}
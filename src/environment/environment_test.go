@@ -0,0 +1,50 @@
+package environment
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// test category lists are parsed and printed in a stable order
+func TestParseToggles(t *testing.T) {
+	toggles, err := ParseToggles("Tools, os")
+	if err != nil || toggles.String() != "os,tools" {
+		t.Fatalf(`ParseToggles("Tools, os") = (%v, %v), expected "os,tools"`, toggles, err)
+	}
+
+	toggles, err = ParseToggles("none")
+	if err != nil || toggles.Any() {
+		t.Fatalf(`ParseToggles("none") = (%v, %v), expected nothing enabled`, toggles, err)
+	}
+
+	if _, err = ParseToggles("os,secrets"); err == nil {
+		t.Fatalf(`ParseToggles("os,secrets") succeeded, expected an unknown category error`)
+	}
+}
+
+// test only enabled categories are collected and the layout skips hidden entries
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src", "deep", "deeper"), 0755)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0755)
+	os.WriteFile(filepath.Join(dir, "go.mod"), nil, 0644)
+
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+
+	c := Collect(Toggles{Files: true})
+	if c.OS != "" || c.Shell != "" || len(c.Tools) != 0 {
+		t.Fatalf("Collect(files) = %+v, expected only the files", c)
+	}
+
+	text := c.String()
+	if !strings.Contains(text, "go.mod") || !strings.Contains(text, "src/deep/") {
+		t.Fatalf("layout is missing entries:\n%s", text)
+	}
+	if strings.Contains(text, ".git") || strings.Contains(text, "deeper") {
+		t.Fatalf("layout lists hidden or too deep entries:\n%s", text)
+	}
+}
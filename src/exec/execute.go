@@ -1,21 +1,203 @@
 package exec
 
 import (
+	"context"
+	"io"
+	"os"
 	"os/exec"
+	"sync"
+	"syscall"
+	"time"
 )
 
+// exit status of fl when it was interrupted, like a shell after Ctrl-C
+const ExitInterrupted = 130
+
+// how long a canceled command has to exit after SIGINT, and again after
+// SIGTERM, before it is killed
+var TerminateGrace = 3 * time.Second
+
 // wrap os.exec struct for decoupling
 type Exec struct {
 	Cmd *exec.Cmd
 }
 
 func Command(result string) Exec {
-	out := exec.Command("bash", "-c", result)
+	return CommandWithShell([]string{"bash", "-c"}, result)
+}
+
+// run the command with a specific interpreter, e.g., []string{"fish", "-c"}
+func CommandWithShell(shell []string, result string) Exec {
+	args := append(append([]string{}, shell[1:]...), result)
+	out := exec.Command(shell[0], args...)
 	return Exec{Cmd: out}
 }
 
+// run the command and return its buffered stdout
 func (ex Exec) Exec() (res string, err error) {
 	var tmp []byte
 	tmp, err = ex.Cmd.Output()
 	return string(tmp), err
 }
+
+// run the command attached to the terminal, its output is written as it is
+// produced; the command runs in its own process group, which is sent SIGINT,
+// then SIGTERM and finally SIGKILL when ctx is canceled; the error is only
+// set if the command could not be started or waited on, a command that ran
+// and failed is reported by its exit status
+func (ex Exec) Stream(ctx context.Context) (int, error) {
+	if ex.Cmd.Stdin == nil {
+		ex.Cmd.Stdin = os.Stdin
+	}
+	if ex.Cmd.Stdout == nil {
+		ex.Cmd.Stdout = os.Stdout
+	}
+	if ex.Cmd.Stderr == nil {
+		ex.Cmd.Stderr = os.Stderr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return -1, err
+	}
+
+	foreground := setProcessGroup(ex.Cmd)
+	if err := ex.Cmd.Start(); err != nil {
+		return -1, err
+	}
+	started(ex.Cmd.Process)
+	defer exited(ex.Cmd.Process)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			terminate(ex.Cmd.Process, done)
+		case <-done:
+		}
+	}()
+
+	err := ex.Cmd.Wait()
+	close(done)
+	<-stopped
+	if foreground {
+		restoreForeground()
+	}
+
+	if _, ok := err.(*exec.ExitError); ok {
+		return ExitCode(err), nil
+	}
+	return ExitCode(err), err
+}
+
+// interrupt the command's process group, then terminate it and finally kill
+// it if it is still running after each grace period
+func terminate(p *os.Process, done <-chan struct{}) {
+	for _, sig := range []syscall.Signal{syscall.SIGINT, syscall.SIGTERM} {
+		signalGroup(p, sig)
+		select {
+		case <-done:
+			return
+		case <-time.After(TerminateGrace):
+		}
+	}
+	signalGroup(p, syscall.SIGKILL)
+}
+
+// commands started by Stream that have not exited, so fl can wait for them
+// or kill them before it exits
+var running = struct {
+	sync.Mutex
+	procs map[*os.Process]bool
+	idle  []chan struct{}
+}{procs: map[*os.Process]bool{}}
+
+func started(p *os.Process) {
+	running.Lock()
+	defer running.Unlock()
+	running.procs[p] = true
+}
+
+func exited(p *os.Process) {
+	running.Lock()
+	defer running.Unlock()
+	delete(running.procs, p)
+	if len(running.procs) == 0 {
+		for _, idle := range running.idle {
+			close(idle)
+		}
+		running.idle = nil
+	}
+}
+
+// a channel that is closed once no command started by Stream is running
+func Idle() <-chan struct{} {
+	running.Lock()
+	defer running.Unlock()
+	idle := make(chan struct{})
+	if len(running.procs) == 0 {
+		close(idle)
+	} else {
+		running.idle = append(running.idle, idle)
+	}
+	return idle
+}
+
+// kill the process groups of all running commands
+func KillAll() {
+	running.Lock()
+	defer running.Unlock()
+	for p := range running.procs {
+		signalGroup(p, syscall.SIGKILL)
+	}
+}
+
+// most bytes of stderr kept by StreamCapture
+const MaxCapturedStderr = 4 * 1024
+
+// run the command like Stream, also returning the end of what it wrote to
+// stderr so a failed command can be explained or corrected later
+func (ex Exec) StreamCapture(ctx context.Context) (int, string, error) {
+	if ex.Cmd.Stderr == nil {
+		ex.Cmd.Stderr = os.Stderr
+	}
+
+	tail := &TailBuffer{Max: MaxCapturedStderr}
+	ex.Cmd.Stderr = io.MultiWriter(ex.Cmd.Stderr, tail)
+	code, err := ex.Stream(ctx)
+	return code, tail.String(), err
+}
+
+// a writer that keeps the last Max bytes written to it
+type TailBuffer struct {
+	Max  int
+	data []byte
+}
+
+func (b *TailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if len(b.data) > b.Max {
+		b.data = append(b.data[:0], b.data[len(b.data)-b.Max:]...)
+	}
+	return len(p), nil
+}
+
+func (b *TailBuffer) String() string {
+	return string(b.data)
+}
+
+// exit status of a command that ran, 0 on success and -1 if it did not run;
+// a command killed by a signal reports 128 plus the signal number like a shell
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	}
+	return -1
+}
@@ -6,11 +6,13 @@ package exec
  */
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 )
 
 // test cmd gen when quotes are involved
@@ -281,3 +283,73 @@ func TestExecution(t *testing.T) {
 		t.Fatalf(`Testfile not properly deleted`)
 	}
 }
+
+// test streamed output is written as produced and the exit status is returned
+func TestStream(t *testing.T) {
+	var stdout, stderr strings.Builder
+
+	Cmd := Command("echo out; echo err >&2; exit 7")
+	Cmd.Cmd.Stdout = &stdout
+	Cmd.Cmd.Stderr = &stderr
+
+	code, err := Cmd.Stream(context.Background())
+	if code != 7 || err != nil {
+		t.Fatalf(`Stream() = (%d, %v), expected (7, nil)`, code, err)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Fatalf(`Stream() wrote ("%s", "%s"), expected ("out\n", "err\n")`, stdout.String(), stderr.String())
+	}
+
+	code, _ = Command("kill -TERM $$").Stream(context.Background())
+	if code != 128+15 {
+		t.Fatalf(`Stream() of a killed command = %d, expected %d`, code, 128+15)
+	}
+}
+
+// test the end of stderr is captured while it is still written through
+func TestStreamCapture(t *testing.T) {
+	var stderr strings.Builder
+
+	Cmd := Command("seq 1 2000 >&2; echo missing file >&2; exit 2")
+	Cmd.Cmd.Stdout = &strings.Builder{}
+	Cmd.Cmd.Stderr = &stderr
+
+	code, captured, err := Cmd.StreamCapture(context.Background())
+	if code != 2 || err != nil {
+		t.Fatalf(`StreamCapture() = (%d, %v), expected (2, nil)`, code, err)
+	}
+	if !strings.HasSuffix(captured, "2000\nmissing file\n") || len(captured) != MaxCapturedStderr {
+		t.Fatalf(`StreamCapture() captured %d bytes ending in "%s", expected the last %d bytes`, len(captured), captured[len(captured)-20:], MaxCapturedStderr)
+	}
+	if !strings.HasPrefix(stderr.String(), "1\n2\n") {
+		t.Fatalf(`StreamCapture() wrote "%s...", expected all of stderr`, stderr.String()[:10])
+	}
+}
+
+// test a canceled command is interrupted, and killed if it ignores the signals
+func TestStreamCanceled(t *testing.T) {
+	defer func(grace time.Duration) { TerminateGrace = grace }(TerminateGrace)
+	TerminateGrace = 200 * time.Millisecond
+
+	for _, c := range []struct {
+		cmd  string
+		code int
+	}{
+		{"sleep 30", 128 + 2},
+		{"trap '' INT TERM; sleep 30", 128 + 9},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		start := time.Now()
+		code, err := Command(c.cmd).Stream(ctx)
+		cancel()
+		if code != c.code || err != nil || time.Since(start) > 5*time.Second {
+			t.Fatalf(`Stream("%s") canceled = (%d, %v) after %v, expected %d`, c.cmd, code, err, time.Since(start), c.code)
+		}
+	}
+
+	select {
+	case <-Idle():
+	default:
+		t.Fatalf("Idle() is not closed after the commands exited")
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+
+package exec
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// run the command in its own process group so it can be stopped as a whole;
+// when fl owns the terminal the group is put in the foreground, so Ctrl-C
+// and job control reach the command like in a shell; returns whether the
+// terminal has to be taken back once the command exits
+func setProcessGroup(cmd *exec.Cmd) bool {
+	attr := &syscall.SysProcAttr{Setpgid: true}
+	cmd.SysProcAttr = attr
+
+	tty := int(os.Stdin.Fd())
+	foreground, err := unix.IoctlGetInt(tty, unix.TIOCGPGRP)
+	if err != nil || foreground != unix.Getpgrp() {
+		return false
+	}
+	attr.Foreground, attr.Ctty = true, tty
+	return true
+}
+
+// put fl's process group back in the foreground of the terminal
+func restoreForeground() {
+	// a background group changing the foreground is sent SIGTTOU
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	unix.IoctlSetPointerInt(int(os.Stdin.Fd()), unix.TIOCSPGRP, unix.Getpgrp())
+}
+
+// send sig to every process in the command's group
+func signalGroup(p *os.Process, sig syscall.Signal) {
+	syscall.Kill(-p.Pid, sig)
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package exec
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// windows has no process groups that can be signaled, the command is killed instead
+func setProcessGroup(cmd *exec.Cmd) bool {
+	return false
+}
+
+func restoreForeground() {}
+
+func signalGroup(p *os.Process, sig syscall.Signal) {
+	p.Kill()
+}
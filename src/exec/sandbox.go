@@ -0,0 +1,421 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"fl/utils"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// directories larger than this are not copied into a sandbox
+const MaxSandboxBytes = 512 * 1024 * 1024
+
+// changed files larger than this are listed without a diff
+const maxDiffBytes = 1024 * 1024
+
+// a scratch copy of a directory to run commands in before running them for real
+type Sandbox struct {
+	Source  string   // the directory that was copied
+	Dir     string   // the scratch copy
+	Skipped []string // entries that could not be read and were not copied, relative to Source
+}
+
+type ChangeKind string
+
+const (
+	Created  ChangeKind = "created"
+	Modified ChangeKind = "modified"
+	Deleted  ChangeKind = "deleted"
+)
+
+// a file that differs between the sandbox and its source
+type Change struct {
+	Path string // relative to the sandbox root
+	Kind ChangeKind
+	Diff string // unified diff for text files, empty for binary files
+}
+
+// copy dir into a new temporary directory, using copy-on-write clones where
+// the filesystem supports them; entries that cannot be read are skipped
+func NewSandbox(dir string) (*Sandbox, error) {
+	size, unreadable, err := dirSize(dir)
+	if err != nil {
+		return nil, err
+	}
+	if size > MaxSandboxBytes {
+		return nil, fmt.Errorf("%s is too large to copy into a sandbox (%d MB)", dir, size/(1024*1024))
+	}
+
+	tmp, err := os.MkdirTemp("", "fl-sandbox-")
+	if err != nil {
+		return nil, err
+	}
+
+	sandbox := &Sandbox{Source: dir, Dir: filepath.Join(tmp, filepath.Base(dir))}
+	skipped, err := cloneDir(dir, sandbox.Dir)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, p := range append(unreadable, skipped...) {
+		if !seen[p] {
+			seen[p] = true
+			sandbox.Skipped = append(sandbox.Skipped, p)
+		}
+	}
+	sort.Strings(sandbox.Skipped)
+	return sandbox, nil
+}
+
+// whether a path relative to the source is a skipped entry or inside one
+func (s *Sandbox) skipped(rel string) bool {
+	for _, p := range s.Skipped {
+		if rel == p || strings.HasPrefix(rel, p+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// a command that runs inside the sandbox
+func (s *Sandbox) Command(shell []string, result string) Exec {
+	ex := CommandWithShell(shell, result)
+	ex.Cmd.Dir = s.Dir
+	return ex
+}
+
+// remove the scratch copy
+func (s *Sandbox) Close() error {
+	return os.RemoveAll(filepath.Dir(s.Dir))
+}
+
+// files created, modified or deleted in the sandbox relative to its source
+func (s *Sandbox) Changes() ([]Change, error) {
+	before, err := listFiles(s.Source)
+	if err != nil {
+		return nil, err
+	}
+	after, err := listFiles(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// skipped entries are missing from the sandbox, they were not deleted
+	for path := range before {
+		if s.skipped(path) {
+			delete(before, path)
+			delete(after, path)
+		}
+	}
+
+	changes := []Change{}
+	for path := range after {
+		if _, ok := before[path]; !ok {
+			diff, _ := fileDiff("", filepath.Join(s.Dir, path), path)
+			changes = append(changes, Change{Path: path, Kind: Created, Diff: diff})
+		}
+	}
+
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			diff, _ := fileDiff(filepath.Join(s.Source, path), "", path)
+			changes = append(changes, Change{Path: path, Kind: Deleted, Diff: diff})
+			continue
+		}
+
+		same, err := sameContents(filepath.Join(s.Source, path), filepath.Join(s.Dir, path))
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			diff, _ := fileDiff(filepath.Join(s.Source, path), filepath.Join(s.Dir, path), path)
+			changes = append(changes, Change{Path: path, Kind: Modified, Diff: diff})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// summary of the changes followed by the diffs of text files
+func FormatChanges(changes []Change) string {
+	if len(changes) == 0 {
+		return "No files were created, modified or deleted.\n"
+	}
+
+	summary, diffs := "", ""
+	for _, c := range changes {
+		summary += fmt.Sprintf("  %-9s %s\n", c.Kind, c.Path)
+		diffs += c.Diff
+	}
+
+	if diffs != "" {
+		summary += "\n" + diffs
+	}
+	return summary
+}
+
+// total size of the regular files under dir and the entries that cannot be read
+func dirSize(dir string) (int64, []string, error) {
+	size := int64(0)
+	unreadable := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			rel, _ := filepath.Rel(dir, path)
+			unreadable = append(unreadable, rel)
+			return nil
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size, unreadable, err
+}
+
+// clone with cp where it can use reflinks, otherwise copy file by file; cp
+// fails on entries it cannot read, which the copy skips and returns
+func cloneDir(src string, dst string) ([]string, error) {
+	var clone *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		clone = exec.Command("cp", "-a", "--reflink=auto", src, dst)
+	case "darwin":
+		clone = exec.Command("cp", "-c", "-R", "-p", src, dst)
+	}
+
+	if clone != nil && clone.Run() == nil {
+		return nil, nil
+	}
+
+	os.RemoveAll(dst)
+	return copyDir(src, dst)
+}
+
+func copyDir(src string, dst string) ([]string, error) {
+	skipped := []string{}
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		rel, relErr := filepath.Rel(src, path)
+		if relErr != nil {
+			return relErr
+		}
+		if err != nil {
+			if path == src {
+				return err
+			}
+			skipped = append(skipped, rel)
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			skipped = append(skipped, rel)
+			return nil
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				skipped = append(skipped, rel)
+				return nil
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			err := copyFile(path, target, info.Mode().Perm())
+			if errors.Is(err, fs.ErrPermission) {
+				skipped = append(skipped, rel)
+				return nil
+			}
+			return err
+		}
+		// sockets, devices and pipes are not copied
+		return nil
+	})
+	return skipped, err
+}
+
+func copyFile(src string, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// regular files and symlinks under dir, by path relative to dir
+func listFiles(dir string) (map[string]fs.FileMode, error) {
+	files := map[string]fs.FileMode{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		// the sandbox skipped what cannot be read
+		if err != nil && path != dir {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = d.Type()
+		return nil
+	})
+	return files, err
+}
+
+func sameContents(a string, b string) (bool, error) {
+	infoA, err := os.Lstat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Lstat(b)
+	if err != nil {
+		return false, err
+	}
+
+	if infoA.Mode() != infoB.Mode() {
+		return false, nil
+	}
+
+	if infoA.Mode()&fs.ModeSymlink != 0 {
+		linkA, _ := os.Readlink(a)
+		linkB, _ := os.Readlink(b)
+		return linkA == linkB, nil
+	}
+
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	fileA, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fileA.Close()
+	fileB, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fileB.Close()
+
+	// compare a chunk at a time, a sandbox may hold files too large to read at once
+	bufA := make([]byte, 64*1024)
+	bufB := make([]byte, len(bufA))
+	for {
+		n, errA := io.ReadFull(fileA, bufA)
+		m, errB := io.ReadFull(fileB, bufB)
+		if !bytes.Equal(bufA[:n], bufB[:m]) {
+			return false, nil
+		}
+		doneA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		doneB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		if errA != nil && !doneA {
+			return false, errA
+		}
+		if errB != nil && !doneB {
+			return false, errB
+		}
+		if doneA || doneB {
+			return doneA == doneB, nil
+		}
+	}
+}
+
+var errTooLarge = errors.New("too large to diff")
+
+// unified diff of two files, either may be empty for a created or deleted file;
+// binary files and files larger than maxDiffBytes have none
+func fileDiff(oldPath string, newPath string, name string) (string, error) {
+	read := func(path string) ([]byte, error) {
+		if path == "" {
+			return nil, nil
+		}
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			return nil, err
+		}
+		if info.Size() > maxDiffBytes {
+			return nil, errTooLarge
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(io.LimitReader(f, maxDiffBytes+1))
+	}
+
+	oldData, err := read(oldPath)
+	if err == errTooLarge {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	newData, err := read(newPath)
+	if err == errTooLarge {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	// a file that grew while it was read is not diffed either
+	if len(oldData) > maxDiffBytes || len(newData) > maxDiffBytes || !isText(oldData) || !isText(newData) {
+		return "", nil
+	}
+
+	oldName, newName := "a/"+name, "b/"+name
+	if oldPath == "" {
+		oldName = "/dev/null"
+	}
+	if newPath == "" {
+		newName = "/dev/null"
+	}
+	return utils.UnifiedDiff(oldName, newName, string(oldData), string(newData)), nil
+}
+
+func isText(data []byte) bool {
+	sample := data
+	if len(sample) > 8192 {
+		sample = sample[:8192]
+	}
+	return !bytes.ContainsRune(sample, 0) && utf8.Valid(data)
+}
@@ -0,0 +1,138 @@
+package exec
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// test a command run in a sandbox leaves the directory alone and its changes are reported
+func TestSandboxChanges(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"keep.txt": "same\n", "edit.txt": "one\ntwo\n", "gone.txt": "bye\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+
+	sandbox, err := NewSandbox(dir)
+	if err != nil {
+		t.Fatalf("NewSandbox: %v", err)
+	}
+	defer sandbox.Close()
+
+	cmd := "echo three >> edit.txt && rm gone.txt && echo hi > new.txt"
+	if _, err := sandbox.Command([]string{"bash", "-c"}, cmd).Exec(); err != nil {
+		t.Fatalf("running in sandbox: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "gone.txt")); err != nil {
+		t.Fatalf("the source directory was changed: %v", err)
+	}
+
+	changes, err := sandbox.Changes()
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+
+	want := map[string]ChangeKind{"edit.txt": Modified, "gone.txt": Deleted, "new.txt": Created}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for _, c := range changes {
+		if want[c.Path] != c.Kind {
+			t.Fatalf("%s: got %s, want %s", c.Path, c.Kind, want[c.Path])
+		}
+	}
+
+	if diff := changes[0].Diff; !strings.Contains(diff, "+three") {
+		t.Fatalf("diff of edit.txt does not show the added line:\n%s", diff)
+	}
+}
+
+// test files are compared past the first chunk
+func TestSameContents(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte(strings.Repeat("x", 200*1024))
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+		return path
+	}
+	a := write("a", data)
+	b := write("b", data)
+	changed := append([]byte{}, data...)
+	changed[len(changed)-1] = 'y'
+	c := write("c", changed)
+
+	if same, err := sameContents(a, b); err != nil || !same {
+		t.Fatalf("sameContents() of equal files = (%v, %v), expected true", same, err)
+	}
+	if same, err := sameContents(a, c); err != nil || same {
+		t.Fatalf("sameContents() of files that differ in the last byte = (%v, %v), expected false", same, err)
+	}
+}
+
+// test entries that cannot be read are skipped instead of failing the sandbox
+// and are not reported as deleted
+func TestSandboxUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read every directory")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ok.txt"), []byte("ok\n"), 0644); err != nil {
+		t.Fatalf("writing ok.txt: %v", err)
+	}
+	locked := filepath.Join(dir, "locked")
+	if err := os.Mkdir(locked, 0755); err != nil {
+		t.Fatalf("creating locked: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(locked, "secret.txt"), []byte("secret\n"), 0644); err != nil {
+		t.Fatalf("writing secret.txt: %v", err)
+	}
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatalf("locking: %v", err)
+	}
+	defer os.Chmod(locked, 0755)
+
+	sandbox, err := NewSandbox(dir)
+	if err != nil {
+		t.Fatalf("NewSandbox with an unreadable directory: %v", err)
+	}
+	defer sandbox.Close()
+
+	if len(sandbox.Skipped) != 1 || sandbox.Skipped[0] != "locked" {
+		t.Fatalf("Skipped = %v, expected [locked]", sandbox.Skipped)
+	}
+	if changes, err := sandbox.Changes(); err != nil || len(changes) != 0 {
+		t.Fatalf("Changes() = (%+v, %v), expected no changes", changes, err)
+	}
+}
+
+// test large files are reported without a diff
+func TestSandboxLargeDiff(t *testing.T) {
+	dir := t.TempDir()
+	large := strings.Repeat("line\n", maxDiffBytes/5+1)
+	if err := os.WriteFile(filepath.Join(dir, "large.txt"), []byte(large), 0644); err != nil {
+		t.Fatalf("writing large.txt: %v", err)
+	}
+
+	sandbox, err := NewSandbox(dir)
+	if err != nil {
+		t.Fatalf("NewSandbox: %v", err)
+	}
+	defer sandbox.Close()
+
+	if _, err := sandbox.Command([]string{"bash", "-c"}, "echo more >> large.txt").Exec(); err != nil {
+		t.Fatalf("running in sandbox: %v", err)
+	}
+
+	changes, err := sandbox.Changes()
+	if err != nil || len(changes) != 1 || changes[0].Kind != Modified || changes[0].Diff != "" {
+		t.Fatalf("Changes() = (%+v, %v), expected large.txt modified without a diff", changes, err)
+	}
+}
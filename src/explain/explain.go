@@ -0,0 +1,37 @@
+package explain
+
+import (
+	"fl/api"
+	"fmt"
+
+	markdown "github.com/MichaelMure/go-term-markdown"
+)
+
+func formatExplanation(cmd string, res *api.ExplainResult) string {
+	explanation := fmt.Sprintf("    %s\n\n", cmd)
+
+	if res.Summary != "" {
+		explanation += fmt.Sprintf("%s\n\n", res.Summary)
+	}
+
+	for i, s := range res.Stages {
+		explanation += fmt.Sprintf("**Stage %d**: `%s`\n\n", i+1, s.Command)
+		if s.Description != "" {
+			explanation += fmt.Sprintf("%s\n\n", s.Description)
+		}
+		for _, f := range s.Flags {
+			explanation += fmt.Sprintf("- `%s` %s\n", f.Flag, f.Description)
+		}
+		if len(s.Flags) > 0 {
+			explanation += "\n"
+		}
+	}
+
+	return explanation
+}
+
+// render the explanation of a generated command to the terminal
+func Show(cmd string, res *api.ExplainResult) {
+	result := markdown.Render(formatExplanation(cmd, res), 80, 0)
+	fmt.Println(string(result))
+}
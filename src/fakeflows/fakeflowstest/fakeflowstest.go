@@ -0,0 +1,33 @@
+// helpers for tests that talk to a fake Flows server, kept out of package
+// fakeflows so that the fl binary does not link the testing package
+package fakeflowstest
+
+import (
+	"fl/api"
+	"fl/fakeflows"
+	"testing"
+)
+
+// start a fake server for the duration of a test and redirect the API to it
+func Start(t testing.TB) *fakeflows.Server {
+	t.Helper()
+
+	s, err := fakeflows.New("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start fake flows server: %v", err)
+	}
+
+	restore, err := fakeflows.Redirect(s.URL)
+	if err != nil {
+		s.Close()
+		t.Fatalf("failed to redirect to fake flows server: %v", err)
+	}
+	api.SetEndpoints(api.EndpointConfig{Paths: map[string]string{api.EndpointExplain: fakeflows.ExplainPath}})
+
+	t.Cleanup(func() {
+		api.SetEndpoints(api.EndpointConfig{})
+		restore()
+		s.Close()
+	})
+	return s
+}
@@ -0,0 +1,49 @@
+package fakeflows
+
+import (
+	"fl/api"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// hosts of the live services that are redirected to the fake
+var redirectedHosts = map[string]string{
+	strings.TrimPrefix(api.DefaultBaseURL, "https://"): "",
+	"api.ipify.org": ExternalIPPath,
+}
+
+// an http.RoundTripper that sends requests for the live services to the fake
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, ok := redirectedHosts[req.URL.Host]
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+	if path != "" {
+		req.URL.Path = path
+	}
+	return t.next.RoundTrip(req)
+}
+
+// send all requests for the live Flows API through http.DefaultTransport to
+// the fake at serverURL, and return a function that restores the transport
+func Redirect(serverURL string) (restore func(), err error) {
+	target, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := http.DefaultTransport
+	http.DefaultTransport = redirectTransport{target: target, next: previous}
+	return func() { http.DefaultTransport = previous }, nil
+}
@@ -0,0 +1,369 @@
+package fakeflows
+
+import (
+	"encoding/json"
+	"fl/api"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the Flows endpoints served by the fake
+type Endpoint string
+
+const (
+	Generate             Endpoint = api.EndpointGenerate
+	Explain              Endpoint = api.EndpointExplain
+	LoginGuest           Endpoint = api.EndpointLoginGuest
+	LoginGitHub          Endpoint = api.EndpointLoginGitHub
+	StartSubscription    Endpoint = api.EndpointStartSubscription
+	CancelSubscription   Endpoint = api.EndpointCancelSubscription
+	StatusOfSubscription Endpoint = api.EndpointStatusSubscription
+
+	// path used in place of the external IP lookup during guest login
+	ExternalIPPath = "/ip"
+
+	// the hosted deployment has no explain flow, the fake serves one here
+	ExplainPath = "/api/explain"
+
+	// quota for users that do not set one
+	DefaultQuota = 100
+)
+
+// the path of each endpoint is the same as the live API so only the host
+// changes, explain is configured with ExplainPath
+var endpointPaths = map[Endpoint]string{
+	Generate:             api.DefaultPath(api.EndpointGenerate),
+	Explain:              ExplainPath,
+	LoginGuest:           api.DefaultPath(api.EndpointLoginGuest),
+	LoginGitHub:          api.DefaultPath(api.EndpointLoginGitHub),
+	StartSubscription:    api.DefaultPath(api.EndpointStartSubscription),
+	CancelSubscription:   api.DefaultPath(api.EndpointCancelSubscription),
+	StatusOfSubscription: api.DefaultPath(api.EndpointStatusSubscription),
+}
+
+// a registered account, identified by its FLID
+type User struct {
+	FLID   string
+	Quota  int    // remaining generations before the quota is exhausted, negative for unlimited
+	Status string // subscription status: guest, default, paid or canceling
+
+	canceledAt int64
+}
+
+// a canned reply that takes precedence over the simulated behavior of an endpoint
+type Response struct {
+	Status int           // HTTP status, defaults to 200
+	Output interface{}   // wrapped in the {"Output": ...} envelope unless Raw is set
+	Raw    string        // sent verbatim
+	Delay  time.Duration // wait before replying, or until the client gives up
+}
+
+// a request received by the fake
+type Request struct {
+	Endpoint Endpoint
+	Input    map[string]interface{}
+}
+
+type Server struct {
+	URL string
+
+	mu        sync.Mutex
+	users     map[string]*User
+	tokens    map[string]string // GitHub access token -> FLID
+	commands  map[string]string // prompt -> generated command
+	command   string            // generated when no prompt matches
+	scripted  map[Endpoint][]Response
+	requests  []Request
+	guests    int
+	ip        string
+	server    *http.Server
+	createdAt time.Time
+}
+
+// start a fake Flows server listening on addr (e.g., "127.0.0.1:0")
+func New(addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		URL:       "http://" + listener.Addr().String(),
+		users:     map[string]*User{},
+		tokens:    map[string]string{},
+		commands:  map[string]string{},
+		command:   "echo 'hello from fake flows'",
+		scripted:  map[Endpoint][]Response{},
+		ip:        "127.0.0.1",
+		createdAt: time.Now(),
+	}
+	s.server = &http.Server{Handler: s}
+
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// URL of an endpoint on this server
+func (s *Server) EndpointURL(e Endpoint) string {
+	return s.URL + endpointPaths[e]
+}
+
+// register a user, a zero quota means the quota is already exhausted
+func (s *Server) AddUser(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u.Status == "" {
+		u.Status = "default"
+	}
+	s.users[u.FLID] = &u
+}
+
+// the user for an FLID, nil if unknown
+func (s *Server) User(flid string) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[flid]; ok {
+		copy := *u
+		return &copy
+	}
+	return nil
+}
+
+// map a GitHub access token to the FLID returned by the GitHub login endpoint
+func (s *Server) AddGitHubToken(token string, flid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[token] = flid
+}
+
+// set the command generated for a prompt, or for every other prompt if the prompt is empty
+func (s *Server) SetCommand(prompt string, cmd string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if prompt == "" {
+		s.command = cmd
+	} else {
+		s.commands[prompt] = cmd
+	}
+}
+
+// queue canned responses for an endpoint, each is used once and in order
+func (s *Server) Script(e Endpoint, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scripted[e] = append(s.scripted[e], responses...)
+}
+
+// all requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request{}, s.requests...)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == ExternalIPPath {
+		io.WriteString(w, s.ip)
+		return
+	}
+
+	endpoint := Endpoint("")
+	for e, path := range endpointPaths {
+		if path == r.URL.Path {
+			endpoint = e
+		}
+	}
+
+	if endpoint == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body := struct {
+		Input map[string]interface{} `json:"Input"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// a scripted response may be delayed, the other requests are not blocked meanwhile
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Endpoint: endpoint, Input: body.Input})
+	queue := s.scripted[endpoint]
+	if len(queue) > 0 {
+		s.scripted[endpoint] = queue[1:]
+	}
+	s.mu.Unlock()
+
+	if len(queue) > 0 {
+		if queue[0].Delay > 0 {
+			select {
+			case <-time.After(queue[0].Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		s.reply(w, queue[0])
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	input := func(key string) string {
+		v, _ := body.Input[key].(string)
+		return v
+	}
+
+	switch endpoint {
+	case Generate:
+		candidates, _ := body.Input["candidates"].(float64)
+		s.reply(w, Response{Output: s.generate(input("prompt"), input("flid"), int(candidates))})
+	case Explain:
+		s.reply(w, Response{Output: s.explain(input("cmd"), input("flid"))})
+	case LoginGuest:
+		s.reply(w, Response{Output: s.loginGuest(input("ip"))})
+	case LoginGitHub:
+		s.reply(w, Response{Output: api.LoginResult{FLID: s.tokens[input("token")]}})
+	case StartSubscription, CancelSubscription, StatusOfSubscription:
+		s.reply(w, Response{Output: s.subscription(endpoint, input("flid"))})
+	}
+}
+
+func (s *Server) reply(w http.ResponseWriter, r Response) {
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(r.Status)
+
+	if r.Raw != "" {
+		io.WriteString(w, r.Raw)
+		return
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		Output interface{} `json:"Output"`
+	}{r.Output})
+}
+
+func (s *Server) generate(prompt string, flid string, candidates int) api.GeneratedCommandResult {
+	u, ok := s.users[flid]
+	if !ok {
+		return api.GeneratedCommandResult{Valid: false}
+	}
+
+	cmd, ok := s.commands[prompt]
+	if !ok {
+		cmd = s.command
+	}
+	res := api.GeneratedCommandResult{Valid: true, Cmd: cmd}
+
+	// alternatives are the command with a numbered comment
+	if candidates > 1 {
+		res.Candidates = []api.Candidate{{Cmd: cmd, Description: "The command from fake flows."}}
+		for i := 2; i <= candidates; i++ {
+			res.Candidates = append(res.Candidates, api.Candidate{Cmd: fmt.Sprintf("%s # alternative %d", cmd, i), Description: fmt.Sprintf("Alternative %d from fake flows.", i)})
+		}
+	}
+
+	if u.Quota == 0 {
+		res.Quota = true
+		return res
+	}
+	if u.Quota > 0 {
+		u.Quota--
+	}
+
+	return res
+}
+
+func (s *Server) explain(cmd string, flid string) api.ExplainResult {
+	u, ok := s.users[flid]
+	if !ok {
+		return api.ExplainResult{Valid: false}
+	}
+	if u.Quota == 0 {
+		return api.ExplainResult{Valid: true, Quota: true}
+	}
+
+	res := api.ExplainResult{Valid: true, Summary: "Explanation from fake flows."}
+	for _, stage := range strings.Split(cmd, "|") {
+		fields := strings.Fields(stage)
+		if len(fields) == 0 {
+			continue
+		}
+
+		explained := api.ExplainedStage{Command: strings.TrimSpace(stage), Description: "Runs " + fields[0] + "."}
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "-") {
+				explained.Flags = append(explained.Flags, api.ExplainedFlag{Flag: f, Description: "Option " + f + "."})
+			}
+		}
+		res.Stages = append(res.Stages, explained)
+	}
+	return res
+}
+
+func (s *Server) loginGuest(ip string) api.LoginResult {
+	if ip == "" {
+		return api.LoginResult{}
+	}
+
+	s.guests++
+	flid := fmt.Sprintf("guest-%d", s.guests)
+	s.users[flid] = &User{FLID: flid, Quota: DefaultQuota, Status: "guest"}
+	return api.LoginResult{FLID: flid}
+}
+
+func (s *Server) subscription(endpoint Endpoint, flid string) api.SubscriptionResult {
+	u, ok := s.users[flid]
+	if !ok {
+		return api.SubscriptionResult{Error: "invalid flid"}
+	}
+
+	created := json.Number(fmt.Sprint(s.createdAt.Unix()))
+
+	switch endpoint {
+	case StartSubscription:
+		if u.Status == "guest" || u.Status == "default" {
+			return api.SubscriptionResult{Status: "guest", SubscriptionURL: s.URL + "/subscribe"}
+		}
+
+	case CancelSubscription:
+		if u.Status != "paid" {
+			return api.SubscriptionResult{Error: "no active subscription"}
+		}
+		u.Status = "canceling"
+		u.canceledAt = time.Now().Unix()
+	}
+
+	return api.SubscriptionResult{
+		Status:      u.Status,
+		Created:     created,
+		Canceled_At: json.Number(fmt.Sprint(u.canceledAt)),
+		Cancel_At:   json.Number(fmt.Sprint(u.canceledAt + 30*24*60*60)),
+	}
+}
@@ -1,114 +1,691 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fl/api" // Add this line to import the auth package
 	"fl/cmd"
+	"fl/credentials"
 	"fl/examples"
 	"fl/exec"
+	"fl/explain"
+	"fl/history"
+	"fl/langtool"
+	"fl/output"
+	"fl/profile"
+	"fl/safety"
+	"fl/tldr"
 	"fl/utils"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
 	home, _ := os.UserHomeDir()
-	filepath := filepath.Join(home, ".flconf")
-	flags := cmd.FlagConfig{}
+	cacheDir, _ := os.UserCacheDir()
+	cacheDir = filepath.Join(cacheDir, "fl")
+	historyFile := filepath.Join(home, ".flhistory")
+	legacyFile := filepath.Join(home, ".flconf")
+	flags := cmd.FlagConfig{HistoryFile: historyFile, CacheDir: cacheDir}
 
-	err := cmd.ReadConfig(filepath, &flags)
+	configDir, err := profile.Dir()
+	if err == nil {
+		flags.Profiles, err = profile.Open(configDir, legacyFile)
+	}
+	if err == nil {
+		flags.Profile, err = flags.Profiles.Select(cmd.ProfileArg(os.Args[1:]))
+	}
+	if err == nil && flags.Profiles.Missing() != "" {
+		fmt.Fprintf(os.Stderr, "Warning: the current profile '%s' does not exist, using the default profile (see fl profile use).\n", flags.Profiles.Missing())
+	}
+	if err != nil {
+		fmt.Printf("Error reading saved configuration: %s\n", err)
+		os.Exit(1)
+	}
+	err = cmd.ReadConfig(flags.Profiles.Path(flags.Profile), &flags)
 	if err != nil {
 		fmt.Printf("Error reading saved configuration: %s\n", err)
 		os.Exit(1)
 	}
 
-	err = cmd.ParseCommandLine(os.Args[1:], filepath, &flags)
+	api.SetEndpoints(flags.EndpointsConf)
+	if err := utils.ConfigureHTTP(flags.HTTPConf); err != nil {
+		fmt.Printf("Error in the HTTP settings: %s\n", err)
+		os.Exit(1)
+	}
+
+	ctx := interruptible()
+	err = cmd.ParseCommandLine(ctx, os.Args[1:], &flags)
+	if err == nil {
+		flags.Output, err = output.Resolve(flags.Output, os.Stdout)
+	}
+	if err != nil && ctx.Err() != nil {
+		os.Exit(exec.ExitInterrupted)
+	}
 	if err != nil {
-		fmt.Printf("Error handling command line arguments: %s\n", err)
+		if flags.Output == output.JSON {
+			result := output.Result{Prompt: flags.Prompt}
+			result.Fail(output.ErrArguments, "Error handling command line arguments: %s", err)
+			result.Write(os.Stdout)
+		} else {
+			fmt.Printf("Error handling command line arguments: %s\n", err)
+		}
 		os.Exit(1)
 	}
 
-	if flags.FLID == "" {
+	if flags.ShowContext {
+		cmd.ShowContext(&flags)
+		os.Exit(0)
+	}
+
+	// only generating with the Flows backend sends the FLID, other commands
+	// never unlock the credential store
+	needsLogin := flags.Prompt != "" && flags.GeneratorConf.RequiresLogin() && !flags.Offline
+	if needsLogin {
+		cmd.LoadFLID(&flags)
+	}
+	if needsLogin && flags.FLID == "" {
+		if flags.Output == output.JSON {
+			result := output.Result{Prompt: flags.Prompt}
+			result.Fail(output.ErrNotLoggedIn, "You are not logged in.")
+			result.Write(os.Stdout)
+			os.Stdout = os.Stderr
+		}
 		cmd.LoginMessage(true)
 		os.Exit(0)
 	}
 
-	utils.Log(flags.Verbose, "Flags: %+v\n", flags)
+	logged := flags
+	logged.FLID = credentials.Mask(flags.FLID)
+	utils.Log(flags.Verbose, "Flags: %+v\n", logged)
 
 	if flags.Prompt == "" {
 		examples.Show()
 	} else {
-		runFL(flags)
+		runFL(ctx, flags)
 	}
 }
 
-func runFL(flags cmd.FlagConfig) {
-	res, err := api.GenerateCommand(flags.Prompt, flags.Langtool, flags.FLID)
+// a context canceled by SIGINT, SIGTERM or SIGHUP; API calls then return and
+// running commands are stopped, and fl exits with ExitInterrupted once the
+// commands are gone, leaving runFL a moment to record the history and write
+// the json result; a second signal kills the commands and exits at once
+func interruptible() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+	go func() {
+		<-signals
+		cancel()
+
+		select {
+		case <-signals:
+		case <-exec.Idle():
+			select {
+			case <-signals:
+			case <-time.After(interruptGrace):
+			}
+		}
+		exec.KillAll()
+
+		atInterrupt.Lock()
+		cleanup := atInterrupt.cleanup
+		atInterrupt.Unlock()
+		if cleanup != nil {
+			cleanup()
+		}
+		os.Exit(exec.ExitInterrupted)
+	}()
+	return ctx
+}
+
+// what the watchdog runs before it exits, so that fl cleans up as it does on
+// any other exit
+var atInterrupt struct {
+	sync.Mutex
+	cleanup func()
+}
+
+func onInterrupt(cleanup func()) {
+	atInterrupt.Lock()
+	defer atInterrupt.Unlock()
+	atInterrupt.cleanup = cleanup
+}
+
+// how long fl may take to finish after it was interrupted
+const interruptGrace = time.Second
+
+func runFL(ctx context.Context, flags cmd.FlagConfig) {
+	start := time.Now()
+	mode := flags.Output
+
+	// a shell widget and eval "$(fl ...)" read the command from stdout, and
+	// json mode writes only the result there, everything else goes to stderr
+	stdout := os.Stdout
+	if flags.Widget {
+		mode = output.Plain
+		flags.Candidates = 1
+	}
+	if mode != output.Text {
+		os.Stdout = os.Stderr
+	}
+
+	// os.Exit skips deferred calls so exits call finish first, which records
+	// the history entry once there is one and writes the json result; the
+	// watchdog calls it too when fl does not exit in time after an interrupt,
+	// so it runs once
+	result := output.Result{Prompt: flags.Prompt}
+	record := func() {}
+	var stdin *os.File
+	var finished sync.Once
+	finish := func() {
+		finished.Do(func() {
+			if ctx.Err() != nil && result.Error == nil {
+				result.Fail(output.ErrInterrupted, "Interrupted")
+			}
+			record()
+			if stdin != nil {
+				stdin.Close()
+				os.Remove(stdin.Name())
+			}
+			if mode == output.JSON {
+				result.Write(stdout)
+			}
+		})
+	}
+	defer finish()
+	onInterrupt(finish)
+
+	fail := func(code string, exit int, format string, args ...interface{}) {
+		if ctx.Err() != nil {
+			code, exit, format, args = output.ErrInterrupted, exec.ExitInterrupted, "Interrupted", nil
+		}
+		fmt.Printf(format+"\n", args...)
+		result.Fail(code, format, args...)
+		finish()
+		os.Exit(exit)
+	}
+
+	// commands from the offline corpus when the API cannot help, they are
+	// only shown and never copied or run
+	suggest := func() []tldr.Match {
+		matches, err := cmd.OfflineSuggestions(&flags)
+		if err != nil {
+			utils.Log(flags.Verbose, "Error reading the offline corpus: %v\n", err)
+			return nil
+		}
+		result.Offline = matches
+		if mode != output.JSON {
+			cmd.ShowOfflineSuggestions(os.Stdout, matches)
+		}
+		return matches
+	}
+
+	if flags.Offline {
+		if len(suggest()) == 0 {
+			result.Fail(output.ErrNoSuggestion, "No offline suggestion matches the prompt.")
+			finish()
+			os.Exit(1)
+		}
+		return
+	}
+
+	gen, err := cmd.NewGenerator(&flags)
+	if err != nil {
+		fail(output.ErrBackend, 1, "Error configuring the generation backend: %v", err)
+	}
+
+	utils.Log(flags.Verbose, "Generating with the %s backend\n", gen.Name())
+
+	envContext := cmd.EnvironmentContext(&flags)
+	if envContext != "" {
+		utils.Log(flags.Verbose, "Environment context:\n%s", envContext)
+	}
+
+	// data piped to fl or given with -f is sent with the prompt
+	data, stdin, err := cmd.PromptData(&flags)
 	if err != nil {
-		fmt.Printf("Error generating a command: %v\n", err)
+		fail(output.ErrArguments, 1, "Error reading data for the prompt: %v", err)
+	}
+	if data != "" {
+		utils.Log(flags.Verbose, "Data sent with the prompt:\n%s", data)
+	}
+
+	res, err := gen.Generate(ctx, api.GenerateRequest{Prompt: flags.Prompt, Language: flags.Langtool, FLID: flags.FLID, Context: envContext, Data: data, Candidates: flags.Candidates})
+	if err != nil && ctx.Err() == nil && utils.Unreachable(err) {
+		fmt.Printf("The API cannot be reached: %v\n\n", err)
+		result.Fail(output.ErrBackend, "Error generating a command: %v", err)
+		suggest()
+		finish()
 		os.Exit(1)
 	}
+	if err != nil {
+		fail(output.ErrBackend, 1, "Error generating a command: %v", err)
+	}
+
+	result.Redactions = cmd.Redactions(gen)
+	if flags.ShowRedactions {
+		cmd.ShowRedactions(gen, flags.Prompt)
+	}
+
+	result.Cached = res.Cached
+	if res.Cached {
+		utils.Log(flags.Verbose, "Using a cached command\n")
+	}
 
 	// invalid token, no command
 	if !res.Valid {
 		fmt.Println("Your access code is invalid.")
 		cmd.LoginMessage(true)
+		result.Fail(output.ErrInvalidToken, "Your access code is invalid.")
 		return
 	}
 
-	fmt.Println(res.Cmd)
+	// the chosen candidate continues as if it was the only command, json
+	// mode reports all of them and continues with the preferred one
+	runChosen := false
+	if len(res.Candidates) > 1 {
+		result.Candidates = res.Candidates
+	}
+	if len(res.Candidates) > 1 && !res.Quota && mode != output.JSON {
+		choice, run, ok := chooseCandidate(ctx, gen, flags, res.Candidates)
+		if !ok {
+			return
+		}
+		res.Cmd, runChosen = choice, run
+	}
+
+	if mode != output.JSON {
+		fmt.Fprintln(stdout, res.Cmd)
+	}
+
+	// the langtool was validated when parsing the command line
+	lt, _ := langtool.Lookup(flags.Langtool)
+	result.Langtool, result.Command = lt.Name, res.Cmd
+
+	// record the generation in the history
+	entry := history.Entry{Prompt: flags.Prompt, Langtool: lt.Name, Cmd: res.Cmd}
+	record = func() {
+		entry.Duration = time.Since(start)
+		_, err := history.Open(flags.HistoryFile, flags.HistoryRetention).Add(entry)
+		if err != nil {
+			utils.Log(flags.Verbose, "Error saving history: %v\n", err)
+		}
+	}
+
+	valid := lt.Validate(res.Cmd)
+	result.Valid = valid == nil
+	if valid != nil {
+		result.ValidationError = valid.Error()
+		fmt.Printf("\nWarning: the generated command may not be valid %s: %v\n", lt.Name, valid)
+	}
 
 	if res.Quota {
-		fmt.Println(`
-Warning: You have exhausted your allowed quota.
-Features will be limited and your access may get cut off entirely.
-Use 'fl subscription login --subscribe' to subscribe and continue using the tool.`)
+		result.QuotaExhausted = true
+		quotaWarning()
+		suggest()
+		return
+	}
+
+	// the widget puts the command on the command line, it is not copied or run
+	if flags.Widget {
 		return
 	}
 
-	// no quota -> no clipboard, prompt or auto-run
-	utils.Clip(res.Cmd)
+	// no quota -> no clipboard, prompt or auto-run; the clipboard is only
+	// used interactively
+	save := func() {
+		entry.Copied = mode == output.Text && utils.Clip(res.Cmd) == nil
 
-	if flags.Outfile != "" {
-		err = os.WriteFile(flags.Outfile, []byte(res.Cmd), 0755)
-		if err != nil {
-			fmt.Printf("Error saving output to file: %s\n", err)
-			os.Exit(1)
+		if flags.Outfile != "" {
+			err = utils.WriteFileAtomic(flags.Outfile, []byte(res.Cmd), 0755)
+			if err != nil {
+				fail(output.ErrOutfile, 1, "Error saving output to file: %s", err)
+			}
 		}
 	}
+	save()
 
-	//TODO
-	/*
-		if flags.Explain {
-			explanation, err := web.ExplainCommand(result, flags.Langtool)
-			if err != nil {
-				fmt.Printf("Failed to call Flows API - %s: %v\n", explanation, err)
-				os.Exit(1)
+	if flags.Explain {
+		explanation, err := gen.Explain(ctx, res.Cmd, flags.Langtool, flags.FLID)
+		switch {
+		// without an explain flow the command is still copied and can be run
+		case errors.Is(err, api.ErrExplainNotConfigured):
+			fmt.Fprintf(os.Stderr, "\nNote: no explanation, %v.\n", err)
+
+		case err != nil:
+			fail(output.ErrExplain, 1, "Error explaining the command: %v", err)
+
+		// like generation, an invalid token or exhausted quota stops here and
+		// the command is not run
+		case !explanation.Valid:
+			fmt.Println("Your access code is invalid.")
+			cmd.LoginMessage(true)
+			result.Fail(output.ErrInvalidToken, "Your access code is invalid.")
+			return
+
+		case explanation.Quota:
+			result.QuotaExhausted = true
+			quotaWarning()
+			return
+
+		default:
+			result.Explanation = explanation
+			if mode != output.JSON {
+				explain.Show(res.Cmd, explanation)
 			}
+		}
+	}
 
-			fmt.Println(explanation)
+	// only run commands that can be executed and passed validation; an invalid
+	// command can still be edited at the prompt, which refuses to run it as is
+	prompting := flags.PromptRun && !flags.AutoExecute && !flags.Sandbox && !runChosen
+	if !lt.Executable() || (valid != nil && !prompting) {
+		if runChosen && !lt.Executable() {
+			fmt.Printf("%s commands cannot be executed\n", lt.Name)
 		}
-	*/
+		return
+	}
+
+	// destructive commands never run automatically and need a typed confirmation
+	report := safety.Analyze(res.Cmd, lt.Name)
+	if report.Dangerous() {
+		result.Warnings = report.Findings
+	}
+	if text := report.String(); text != "" {
+		fmt.Printf("\n%s", text)
+	}
 
 	runIt := false
-	if flags.PromptRun && !flags.AutoExecute {
+	if prompting {
+		// an edited command replaces the generated one everywhere
+		res.Cmd, runIt = promptRun(flags, lt, res.Cmd, &entry)
+		if entry.Edited {
+			result.Command, result.Edited = res.Cmd, true
+			result.Warnings = safety.Analyze(res.Cmd, lt.Name).Findings
+			save()
+		}
+	} else if report.Dangerous() && (flags.PromptRun || flags.AutoExecute || flags.Sandbox || runChosen) {
 		fmt.Println()
-		runIt = utils.PromptYesNo("Would you like to execute the command?")
+		runIt = utils.PromptConfirm("Would you like to execute the command?", safety.ConfirmPhrase)
+	} else if flags.Sandbox {
+		// the sandbox run asks before running the command for real
+		runIt = true
+	} else if runChosen {
+		runIt = true
+	}
+
+	if runIt && flags.Sandbox {
+		runIt = runSandboxed(ctx, lt, res.Cmd, stdin)
+		if ctx.Err() != nil {
+			fail(output.ErrInterrupted, exec.ExitInterrupted, "Interrupted")
+		}
 	}
 
 	// perform the command if autoexecute enabled or user prompted to exec
-	if flags.AutoExecute || runIt {
+	if (flags.AutoExecute && !report.Dangerous() && !flags.Sandbox) || runIt {
 		utils.Log(flags.Verbose, "Executing the generated command...")
 
-		Cmd := exec.Command(res.Cmd)
-		out, err := Cmd.Exec()
+		fmt.Println()
+		Cmd := exec.CommandWithShell(lt.Shell, res.Cmd)
+
+		// the command's output is part of the json result, otherwise it goes
+		// to the real stdout so fl --run can be piped
+		// the command reads the data that was piped to fl
+		if stdin != nil {
+			stdin.Seek(0, io.SeekStart)
+			Cmd.Cmd.Stdin = stdin
+		}
+
+		var cmdStdout, cmdStderr bytes.Buffer
+		if mode == output.JSON {
+			Cmd.Cmd.Stdout, Cmd.Cmd.Stderr = &cmdStdout, &cmdStderr
+		} else {
+			Cmd.Cmd.Stdout = stdout
+		}
+
+		started := time.Now()
+		code, stderr, err := Cmd.StreamCapture(ctx)
+
+		entry.Executed = true
+		entry.ExitCode = code
+		entry.Stderr = stderr
+		result.Execution = &output.Execution{
+			ExitCode:   code,
+			Stdout:     cmdStdout.String(),
+			Stderr:     cmdStderr.String(),
+			DurationMS: time.Since(started).Milliseconds(),
+		}
 
 		if err != nil {
-			fmt.Printf("Error while executing command: %s\n", err)
-			os.Exit(1)
+			fail(output.ErrExecution, 127, "Error while executing command: %s", err)
+		}
+
+		// exit with the command's status so fl can be used in scripts and && chains
+		if ctx.Err() != nil {
+			result.Fail(output.ErrInterrupted, "Interrupted")
+			finish()
+			os.Exit(exec.ExitInterrupted)
 		}
+		if code != 0 {
+			finish()
+			os.Exit(code)
+		}
+	}
+}
+
+// ask whether to run the command, offering to edit it first inline or in the
+// user's editor; returns the command, edited or not, and whether to run it
+func promptRun(flags cmd.FlagConfig, lt *langtool.Langtool, command string, entry *history.Entry) (string, bool) {
+	var earlier []string
+	for {
+		fmt.Println()
+		choice := utils.PromptChoice("Would you like to execute the command, edit it (e) or edit it in $EDITOR (v)?", "y/n/e/v")
+
+		var edited string
+		var err error
+		switch choice {
+		case "y":
+			if err := lt.Validate(command); err != nil {
+				fmt.Printf("The command is not valid %s: %v\n", lt.Name, err)
+				continue
+			}
+
+			// destructive commands need a typed confirmation
+			if report := safety.Analyze(command, lt.Name); report.Dangerous() {
+				if entry.Edited {
+					fmt.Printf("\n%s", report)
+				}
+				fmt.Println()
+				return command, utils.PromptConfirm("Would you like to execute the command?", safety.ConfirmPhrase)
+			}
+			return command, true
+
+		case "e":
+			// earlier commands can be recalled with the arrow keys
+			if earlier == nil {
+				entries, _ := history.Open(flags.HistoryFile, flags.HistoryRetention).List()
+				earlier = []string{}
+				for _, e := range entries {
+					earlier = append(earlier, e.Cmd)
+				}
+			}
+			edited, err = utils.EditLine("> ", command, earlier)
+
+		case "v":
+			edited, err = utils.EditInEditor(command, lt.Extension)
+
+		default:
+			return command, false
+		}
+
+		if err == utils.ErrEditCanceled {
+			continue
+		} else if err != nil {
+			fmt.Printf("Error editing the command: %v\n", err)
+			continue
+		}
+
+		edited = strings.TrimSpace(edited)
+		if edited == "" || edited == command {
+			continue
+		}
+
+		command = edited
+		entry.Cmd, entry.Edited = command, true
+		fmt.Println(command)
+
+		if err := lt.Validate(command); err != nil {
+			fmt.Printf("\nWarning: the edited command may not be valid %s: %v\n", lt.Name, err)
+		}
+	}
+}
+
+// run the command in a scratch copy of the working directory, show what it
+// changed and return whether the user wants to run it for real
+func runSandboxed(ctx context.Context, lt *langtool.Langtool, command string, stdin *os.File) bool {
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Error creating sandbox: %s\n", err)
+		return false
+	}
+
+	sandbox, err := exec.NewSandbox(wd)
+	if err != nil {
+		fmt.Printf("Error creating sandbox: %s\n", err)
+		return false
+	}
+	defer sandbox.Close()
+
+	fmt.Printf("\nRunning in a sandbox copy of %s\n", wd)
+	fmt.Println("Note: only changes inside this directory are captured, absolute paths and $HOME are not sandboxed.")
+	for _, p := range sandbox.Skipped {
+		fmt.Fprintf(os.Stderr, "Warning: could not read %s, it is not in the sandbox.\n", p)
+	}
+
+	// the command reads the data that was piped to fl from the start
+	Cmd := sandbox.Command(lt.Shell, command)
+	if stdin != nil {
+		stdin.Seek(0, io.SeekStart)
+		Cmd.Cmd.Stdin = stdin
+	}
+	code, err := Cmd.Stream(ctx)
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		fmt.Printf("Error running the command in the sandbox: %s\n", err)
+		return false
+	}
+	if code != 0 {
+		fmt.Printf("The command exited with status %d in the sandbox\n", code)
+	}
+
+	changes, err := sandbox.Changes()
+	if err != nil {
+		fmt.Printf("Error comparing the sandbox: %s\n", err)
+		return false
+	}
+
+	fmt.Print(exec.FormatChanges(changes))
+	fmt.Println()
+	return utils.PromptYesNo("Would you like to run the command in " + wd + " for real?")
+}
+
+// list the candidates and let the user copy, run or explain one of them,
+// returns the chosen command, whether to run it and false if none was chosen
+func chooseCandidate(ctx context.Context, gen api.Generator, flags cmd.FlagConfig, candidates []api.Candidate) (string, bool, bool) {
+	for i, c := range candidates {
+		fmt.Printf("%d) %s\n", i+1, c.Cmd)
+		if c.Description != "" {
+			fmt.Printf("   %s\n", c.Description)
+		}
+	}
+
+	// without a terminal to ask, the preferred command is used
+	in := utils.PromptInput()
+	if !utils.IsTerminal(in) {
+		fmt.Println()
+		return candidates[0].Cmd, false, true
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Printf("\nChoose a command: <n> to copy it, r<n> to run it, e<n> to explain it, q to quit: ")
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Println()
+			return "", false, false
+		}
+
+		action, n, ok := parseChoice(line, len(candidates))
+		if !ok {
+			fmt.Printf("Enter a number from 1 to %d, optionally prefixed with r or e.\n", len(candidates))
+			continue
+		}
+
+		switch action {
+		case "q":
+			return "", false, false
+		case "r":
+			return candidates[n-1].Cmd, true, true
+		case "e":
+			explanation, err := gen.Explain(ctx, candidates[n-1].Cmd, flags.Langtool, flags.FLID)
+			if err != nil {
+				fmt.Printf("Error explaining the command: %v\n", err)
+				continue
+			}
+			if !explanation.Valid {
+				fmt.Println("Your access code is invalid.")
+				cmd.LoginMessage(true)
+				continue
+			}
+			if explanation.Quota {
+				quotaWarning()
+				continue
+			}
+			explain.Show(candidates[n-1].Cmd, explanation)
+		default:
+			return candidates[n-1].Cmd, false, true
+		}
+	}
+}
+
+// the API answered without a result because the quota is exhausted
+func quotaWarning() {
+	fmt.Println(`
+Warning: You have exhausted your allowed quota.
+Features will be limited and your access may get cut off entirely.
+Use 'fl subscription login --subscribe' to subscribe and continue using the tool.`)
+	fmt.Println()
+}
+
+// an action (empty to copy, r, e or q) and a candidate number, e.g. "2", "r2" or "e 1"
+func parseChoice(line string, count int) (string, int, bool) {
+	line = strings.ToLower(strings.TrimSpace(line))
+	if line == "q" || line == "quit" {
+		return "q", 0, true
+	}
+
+	action := ""
+	if strings.HasPrefix(line, "r") || strings.HasPrefix(line, "e") {
+		action, line = line[:1], strings.TrimSpace(line[1:])
+	}
 
-		// Print the output
-		fmt.Println(out)
+	n, err := strconv.Atoi(line)
+	if err != nil || n < 1 || n > count {
+		return "", 0, false
 	}
+	return action, n, true
 }
@@ -0,0 +1,230 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fl/utils"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultMaxEntries = 1000
+	DefaultMaxAge     = 90 * 24 * time.Hour
+)
+
+// a generated command and what happened to it
+type Entry struct {
+	ID        int           `json:"id"`
+	Timestamp time.Time     `json:"timestamp"`
+	Prompt    string        `json:"prompt"`
+	Langtool  string        `json:"langtool"`
+	Cmd       string        `json:"cmd"`
+	Edited    bool          `json:"edited"` // the user changed the generated command
+	Copied    bool          `json:"copied"`
+	Executed  bool          `json:"executed"`
+	ExitCode  int           `json:"exit_code"`
+	Stderr    string        `json:"stderr,omitempty"` // the end of the stderr of an executed command
+	Duration  time.Duration `json:"duration"`         // time spent generating and executing the command
+}
+
+// entries older than MaxAge or beyond the newest MaxEntries are pruned on every write
+type Retention struct {
+	MaxEntries int           // zero uses DefaultMaxEntries, negative keeps all entries
+	MaxAge     time.Duration // zero uses DefaultMaxAge, negative keeps entries forever
+}
+
+// a history file with one JSON entry per line, safe for concurrent use by
+// several processes through a lock file next to it; the last id issued is
+// kept in another file next to it so ids are never reused, even after the
+// entries are deleted, pruned or cleared
+type Store struct {
+	path      string
+	retention Retention
+}
+
+func Open(path string, retention Retention) *Store {
+	if retention.MaxEntries == 0 {
+		retention.MaxEntries = DefaultMaxEntries
+	}
+	if retention.MaxAge == 0 {
+		retention.MaxAge = DefaultMaxAge
+	}
+	return &Store{path: path, retention: retention}
+}
+
+// record a new entry and return it with its assigned id and timestamp
+func (s *Store) Add(e Entry) (Entry, error) {
+	var idErr error
+	err := s.update(func(entries []Entry) []Entry {
+		e.ID = s.lastID(entries) + 1
+		idErr = utils.WriteFileAtomic(s.path+".id", []byte(strconv.Itoa(e.ID)), 0600)
+		if e.Timestamp.IsZero() {
+			e.Timestamp = time.Now()
+		}
+		return append(entries, e)
+	})
+	if err == nil {
+		err = idErr
+	}
+	return e, err
+}
+
+// the highest id issued, from the id file or from the entries of a history
+// written before there was one
+func (s *Store) lastID(entries []Entry) int {
+	last := 0
+	if raw, err := os.ReadFile(s.path + ".id"); err == nil {
+		last, _ = strconv.Atoi(strings.TrimSpace(string(raw)))
+	}
+	for _, e := range entries {
+		last = max(last, e.ID)
+	}
+	return last
+}
+
+// all entries, oldest first
+func (s *Store) List() ([]Entry, error) {
+	unlock, err := utils.LockFile(s.path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return s.read()
+}
+
+func (s *Store) Get(id int) (*Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, fmt.Errorf("no history entry with id %d", id)
+}
+
+// entries whose prompt or command contain all words of the query, ignoring case
+func (s *Store) Search(query string) ([]Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	words := strings.Fields(strings.ToLower(query))
+	matches := []Entry{}
+	for _, e := range entries {
+		text := strings.ToLower(e.Prompt + " " + e.Cmd)
+		match := true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				match = false
+				break
+			}
+		}
+		if match {
+			matches = append(matches, e)
+		}
+	}
+	return matches, nil
+}
+
+func (s *Store) Delete(id int) error {
+	found := false
+	err := s.update(func(entries []Entry) []Entry {
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.ID == id {
+				found = true
+			} else {
+				kept = append(kept, e)
+			}
+		}
+		return kept
+	})
+
+	if err == nil && !found {
+		err = fmt.Errorf("no history entry with id %d", id)
+	}
+	return err
+}
+
+func (s *Store) Clear() error {
+	return s.update(func(entries []Entry) []Entry {
+		return nil
+	})
+}
+
+// read, modify, prune and rewrite the history while holding the lock
+func (s *Store) update(modify func([]Entry) []Entry) error {
+	unlock, err := utils.LockFile(s.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	entries = s.prune(modify(entries))
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return utils.WriteFileAtomic(s.path, buf.Bytes(), 0600)
+}
+
+func (s *Store) read() ([]Entry, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		e := Entry{}
+		// skip lines that cannot be parsed rather than losing the whole history
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func (s *Store) prune(entries []Entry) []Entry {
+	if s.retention.MaxAge > 0 {
+		cutoff := time.Now().Add(-s.retention.MaxAge)
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.Timestamp.After(cutoff) {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+
+	if s.retention.MaxEntries > 0 && len(entries) > s.retention.MaxEntries {
+		entries = entries[len(entries)-s.retention.MaxEntries:]
+	}
+	return entries
+}
@@ -0,0 +1,108 @@
+package history
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// test entries get increasing ids and can be found again
+func TestAddGetSearch(t *testing.T) {
+	store := Open(filepath.Join(t.TempDir(), "history"), Retention{})
+
+	first, err := store.Add(Entry{Prompt: "list all files", Cmd: "ls -a"})
+	if err != nil || first.ID != 1 || first.Timestamp.IsZero() {
+		t.Fatalf(`Add() = (%+v, %v), expected id 1 with a timestamp`, first, err)
+	}
+
+	second, _ := store.Add(Entry{Prompt: "count lines in go files", Cmd: "find . -name '*.go' | xargs wc -l"})
+	if second.ID != 2 {
+		t.Fatalf(`Add() assigned id %d, expected 2`, second.ID)
+	}
+
+	e, err := store.Get(2)
+	if err != nil || e.Cmd != second.Cmd {
+		t.Fatalf(`Get(2) = (%+v, %v), expected "%s"`, e, err, second.Cmd)
+	}
+
+	matches, _ := store.Search("GO wc")
+	if len(matches) != 1 || matches[0].ID != 2 {
+		t.Fatalf(`Search("GO wc") = %+v, expected entry 2`, matches)
+	}
+}
+
+// test delete and clear
+func TestDeleteClear(t *testing.T) {
+	store := Open(filepath.Join(t.TempDir(), "history"), Retention{})
+	store.Add(Entry{Cmd: "ls"})
+	store.Add(Entry{Cmd: "pwd"})
+
+	if err := store.Delete(1); err != nil {
+		t.Fatalf(`Delete(1) = %v, expected no error`, err)
+	}
+	if err := store.Delete(1); err == nil {
+		t.Fatalf(`Delete(1) twice expected an error`)
+	}
+
+	entries, _ := store.List()
+	if len(entries) != 1 || entries[0].Cmd != "pwd" {
+		t.Fatalf(`List() = %+v, expected only "pwd"`, entries)
+	}
+
+	store.Clear()
+	entries, _ = store.List()
+	if len(entries) != 0 {
+		t.Fatalf(`List() after Clear() = %+v, expected no entries`, entries)
+	}
+
+	// ids of deleted and cleared entries are not reused
+	if e, _ := store.Add(Entry{Cmd: "date"}); e.ID != 3 {
+		t.Fatalf(`Add() after Clear() = id %d, expected 3`, e.ID)
+	}
+	store.Delete(3)
+	if e, _ := store.Add(Entry{Cmd: "uptime"}); e.ID != 4 {
+		t.Fatalf(`Add() after deleting the last entry = id %d, expected 4`, e.ID)
+	}
+}
+
+// test entries beyond the size and age limits are pruned
+func TestRetention(t *testing.T) {
+	store := Open(filepath.Join(t.TempDir(), "history"), Retention{MaxEntries: 2, MaxAge: time.Hour})
+	store.Add(Entry{Cmd: "old", Timestamp: time.Now().Add(-2 * time.Hour)})
+	store.Add(Entry{Cmd: "one"})
+	store.Add(Entry{Cmd: "two"})
+	store.Add(Entry{Cmd: "three"})
+
+	entries, _ := store.List()
+	if len(entries) != 2 || entries[0].Cmd != "two" || entries[1].Cmd != "three" {
+		t.Fatalf(`List() = %+v, expected "two" and "three"`, entries)
+	}
+}
+
+// test concurrent writers do not lose entries
+func TestConcurrentAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// separate stores, as separate fl processes would have
+			if _, err := Open(path, Retention{}).Add(Entry{Cmd: "ls"}); err != nil {
+				t.Errorf(`Add() = %v, expected no error`, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, _ := Open(path, Retention{}).List()
+	ids := map[int]bool{}
+	for _, e := range entries {
+		ids[e.ID] = true
+	}
+	if len(entries) != 20 || len(ids) != 20 {
+		t.Fatalf(`expected 20 entries with unique ids, got %d entries and %d ids`, len(entries), len(ids))
+	}
+}
@@ -0,0 +1,237 @@
+package langtool
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// a shell or tool that commands can be generated for
+type Langtool struct {
+	Name        string   // canonical name, sent to the backend as the language
+	Aliases     []string // alternate names accepted on the command line
+	Description string
+	Extension   string   // file extension of commands opened in an editor
+	Shell       []string // interpreter used to execute generated commands, nil if not executable
+
+	rules []rule // validation rules applied to generated commands
+}
+
+// a validation rule returns an error describing why a command does not fit the target
+type rule func(cmd string) error
+
+// the default target when no langtool is configured
+const Default = "bash"
+
+var registry = []Langtool{
+	{
+		Name:        "bash",
+		Description: "GNU Bourne-Again SHell",
+		Extension:   ".sh",
+		Shell:       []string{"bash", "-c"},
+		rules:       []rule{syntax("bash", "-n", "-c")},
+	},
+	{
+		Name:        "zsh",
+		Description: "Z shell",
+		Extension:   ".zsh",
+		Shell:       []string{"zsh", "-c"},
+		rules:       []rule{syntax("zsh", "-n", "-c")},
+	},
+	{
+		Name:        "fish",
+		Description: "Friendly interactive shell",
+		Extension:   ".fish",
+		Shell:       []string{"fish", "-c"},
+		rules:       []rule{syntax("fish", "--no-execute", "-c"), forbids("fish", `\[\[|<<`)},
+	},
+	{
+		Name:        "sh",
+		Aliases:     []string{"posix", "dash"},
+		Description: "POSIX shell",
+		Extension:   ".sh",
+		Shell:       []string{"sh", "-c"},
+		rules:       []rule{syntax("sh", "-n", "-c"), forbids("POSIX sh", `\[\[|<<<|\bfunction\s|&>|\$'`)},
+	},
+	{
+		Name:        "powershell",
+		Aliases:     []string{"pwsh", "ps"},
+		Description: "PowerShell",
+		Extension:   ".ps1",
+		Shell:       []string{"pwsh", "-NoProfile", "-Command"},
+		rules:       []rule{parses("pwsh", "-NoProfile", "-NonInteractive", "-Command", psParse)},
+	},
+	{
+		Name:        "jq",
+		Description: "Command-line JSON processor",
+		Extension:   ".sh",
+		Shell:       []string{"bash", "-c"},
+		rules:       []rule{syntax("bash", "-n", "-c"), invokes("jq")},
+	},
+	{
+		Name:        "awk",
+		Aliases:     []string{"gawk", "mawk"},
+		Description: "Pattern scanning and processing language",
+		Extension:   ".sh",
+		Shell:       []string{"bash", "-c"},
+		rules:       []rule{syntax("bash", "-n", "-c"), invokes("awk", "gawk", "mawk", "nawk")},
+	},
+	{
+		Name:        "sed",
+		Description: "Stream editor",
+		Extension:   ".sh",
+		Shell:       []string{"bash", "-c"},
+		rules:       []rule{syntax("bash", "-n", "-c"), invokes("sed", "gsed")},
+	},
+	{
+		Name:        "sql",
+		Description: "SQL query (not executed)",
+		Extension:   ".sql",
+		rules:       []rule{sqlStatement},
+	},
+	{
+		Name:        "kubectl",
+		Aliases:     []string{"k8s", "kubernetes"},
+		Description: "Kubernetes command-line tool",
+		Extension:   ".sh",
+		Shell:       []string{"bash", "-c"},
+		rules:       []rule{syntax("bash", "-n", "-c"), invokes("kubectl")},
+	},
+	{
+		Name:        "git",
+		Description: "Git version control",
+		Extension:   ".sh",
+		Shell:       []string{"bash", "-c"},
+		rules:       []rule{syntax("bash", "-n", "-c"), invokes("git")},
+	},
+}
+
+// find a registered langtool by name or alias, the empty name is the default target
+func Lookup(name string) (*Langtool, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = Default
+	}
+
+	for i := range registry {
+		lt := &registry[i]
+		if lt.Name == name {
+			return lt, nil
+		}
+		for _, a := range lt.Aliases {
+			if a == name {
+				return lt, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported shell or tool '%s' (supported: %s)", name, strings.Join(Names(), ", "))
+}
+
+// canonical names of all registered langtools
+func Names() []string {
+	names := make([]string, len(registry))
+	for i, lt := range registry {
+		names[i] = lt.Name
+	}
+	return names
+}
+
+// all registered langtools
+func All() []Langtool {
+	return registry
+}
+
+// whether generated commands for this target can be executed
+func (lt *Langtool) Executable() bool {
+	return len(lt.Shell) > 0
+}
+
+// check that a generated command is valid for this target
+func (lt *Langtool) Validate(cmd string) error {
+	if strings.TrimSpace(cmd) == "" {
+		return fmt.Errorf("empty command")
+	}
+
+	for _, r := range lt.rules {
+		if err := r(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check syntax with the interpreter in no-exec mode, skipped if the interpreter is not installed
+func syntax(interpreter string, args ...string) rule {
+	return func(cmd string) error {
+		path, err := exec.LookPath(interpreter)
+		if err != nil {
+			return nil
+		}
+
+		var stderr bytes.Buffer
+		check := exec.Command(path, append(args, cmd)...)
+		check.Stderr = &stderr
+		if err := check.Run(); err != nil {
+			return fmt.Errorf("%s syntax error: %s", interpreter, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	}
+}
+
+// PowerShell has no no-exec mode, so its parser checks the command read from stdin
+const psParse = `$errors = $null
+[void][System.Management.Automation.Language.Parser]::ParseInput([Console]::In.ReadToEnd(), [ref]$null, [ref]$errors)
+if ($errors) { $errors | ForEach-Object { [Console]::Error.WriteLine($_.Message) }; exit 1 }`
+
+// check syntax with a parser that reads the command from stdin, skipped if the interpreter is not installed
+func parses(interpreter string, args ...string) rule {
+	return func(cmd string) error {
+		path, err := exec.LookPath(interpreter)
+		if err != nil {
+			return nil
+		}
+
+		var stderr bytes.Buffer
+		check := exec.Command(path, args...)
+		check.Stdin = strings.NewReader(cmd)
+		check.Stderr = &stderr
+		if err := check.Run(); err != nil {
+			return fmt.Errorf("%s syntax error: %s", interpreter, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	}
+}
+
+// reject constructs that are not supported by the target
+func forbids(target string, pattern string) rule {
+	re := regexp.MustCompile(pattern)
+	return func(cmd string) error {
+		if m := re.FindString(cmd); m != "" {
+			return fmt.Errorf("'%s' is not supported by %s", m, target)
+		}
+		return nil
+	}
+}
+
+// require that one of the tools is invoked by some stage of the command
+func invokes(tools ...string) rule {
+	re := regexp.MustCompile(`(^|[\s|;&({/])(` + strings.Join(tools, "|") + `)(\s|$)`)
+	return func(cmd string) error {
+		if !re.MatchString(cmd) {
+			return fmt.Errorf("command does not use %s", tools[0])
+		}
+		return nil
+	}
+}
+
+var sqlKeywords = regexp.MustCompile(`(?i)^\s*(select|insert|update|delete|create|alter|drop|with|truncate|grant|revoke|explain|begin|merge|replace|pragma|show|describe)\b`)
+
+func sqlStatement(cmd string) error {
+	if !sqlKeywords.MatchString(cmd) {
+		return fmt.Errorf("not a SQL statement")
+	}
+	return nil
+}
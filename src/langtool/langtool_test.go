@@ -0,0 +1,88 @@
+package langtool
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// test lookup by name, alias and default
+func TestLookup(t *testing.T) {
+	cases := map[string]string{
+		"":      "bash",
+		"bash":  "bash",
+		"ZSH":   "zsh",
+		"posix": "sh",
+		"pwsh":  "powershell",
+		"k8s":   "kubectl",
+	}
+
+	for name, expected := range cases {
+		lt, err := Lookup(name)
+		if err != nil || lt.Name != expected {
+			t.Fatalf(`Lookup("%s") = (%v, %v), expected "%s"`, name, lt, err, expected)
+		}
+	}
+
+	if _, err := Lookup("cobol"); err == nil {
+		t.Fatalf(`Lookup("cobol") expected an error`)
+	}
+}
+
+// test the tool specific validation rules
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		langtool string
+		cmd      string
+		valid    bool
+	}{
+		{"jq", "jq '.items[]' data.json", true},
+		{"jq", "cat data.json | jq -r .name", true},
+		{"jq", "grep name data.json", false},
+		{"awk", "awk -F, '{print $2}' file.csv", true},
+		{"git", "git log --oneline", true},
+		{"git", "svn log", false},
+		{"kubectl", "kubectl get pods -A", true},
+		{"sql", "SELECT * FROM users;", true},
+		{"sql", "ls -l", false},
+		{"sh", "[[ -f file ]] && echo yes", false},
+		{"sh", "cat <<< hello", false},
+		{"sh", "[ -f file ] && echo yes", true},
+		{"bash", "", false},
+	}
+
+	for _, c := range cases {
+		lt, _ := Lookup(c.langtool)
+		err := lt.Validate(c.cmd)
+		if (err == nil) != c.valid {
+			t.Fatalf(`%s.Validate("%s") = %v, expected valid=%v`, c.langtool, c.cmd, err, c.valid)
+		}
+	}
+}
+
+// test PowerShell commands are checked by its parser when pwsh is installed
+func TestValidatePowerShell(t *testing.T) {
+	if _, err := exec.LookPath("pwsh"); err != nil {
+		t.Skip("pwsh is not installed")
+	}
+
+	lt, _ := Lookup("powershell")
+	if err := lt.Validate("Get-ChildItem -Recurse | Where-Object { $_.Length -gt 1MB }"); err != nil {
+		t.Fatalf(`powershell.Validate() of a valid pipeline = %v, expected nil`, err)
+	}
+	if err := lt.Validate("Get-ChildItem | Where-Object { $_.Length -gt 1MB"); err == nil || !strings.Contains(err.Error(), "pwsh syntax error") {
+		t.Fatalf(`powershell.Validate() of an unclosed block = %v, expected a syntax error`, err)
+	}
+}
+
+// test that only executable targets have a shell
+func TestExecutable(t *testing.T) {
+	for _, lt := range All() {
+		if lt.Name == "sql" && lt.Executable() {
+			t.Fatalf(`sql should not be executable`)
+		}
+		if lt.Name != "sql" && !lt.Executable() {
+			t.Fatalf(`%s should be executable`, lt.Name)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"encoding/json"
+	"fl/api"
+	"fl/redact"
+	"fl/safety"
+	"fl/tldr"
+	"fl/utils"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// output modes
+const (
+	Text  = "text"  // human readable, the default on a terminal
+	Plain = "plain" // only the command on stdout, the default when stdout is not a terminal
+	JSON  = "json"  // a single Result object on stdout
+)
+
+// stable error codes reported in Result.Error
+const (
+	ErrArguments    = "invalid_arguments" // the command line or config could not be parsed
+	ErrNotLoggedIn  = "not_logged_in"     // no access code is configured
+	ErrInvalidToken = "invalid_token"     // the access code was rejected
+	ErrBackend      = "backend_error"     // the generation backend is misconfigured or failed
+	ErrExplain      = "explain_failed"    // the command could not be explained
+	ErrOutfile      = "outfile_failed"    // the command could not be written to --outfile
+	ErrExecution    = "execution_failed"  // the command could not be started
+	ErrInterrupted  = "interrupted"       // fl was stopped with Ctrl-C or a signal
+	ErrNoSuggestion = "no_suggestion"     // nothing in the offline corpus matches the prompt
+)
+
+func Modes() []string {
+	return []string{Text, Plain, JSON}
+}
+
+// the mode to use, an empty mode is text on a terminal and plain otherwise
+func Resolve(mode string, stdout *os.File) (string, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "":
+		if utils.IsTerminal(stdout) {
+			return Text, nil
+		}
+		return Plain, nil
+	case Text, Plain, JSON:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unsupported output '%s' (supported: %s)", mode, strings.Join(Modes(), ", "))
+}
+
+// everything fl did for a prompt
+type Result struct {
+	Prompt          string             `json:"prompt"`
+	Langtool        string             `json:"langtool,omitempty"`
+	Command         string             `json:"command,omitempty"`
+	Candidates      []api.Candidate    `json:"candidates,omitempty"`
+	Valid           bool               `json:"valid"` // the command passed the langtool's validation
+	ValidationError string             `json:"validation_error,omitempty"`
+	QuotaExhausted  bool               `json:"quota_exhausted"`
+	Cached          bool               `json:"cached"`
+	Edited          bool               `json:"edited"`
+	Warnings        []safety.Finding   `json:"warnings"`             // destructive patterns found by the safety checks
+	Redactions      []redact.Redaction `json:"redactions,omitempty"` // secrets replaced before the prompt was sent
+	Explanation     *api.ExplainResult `json:"explanation,omitempty"`
+	Execution       *Execution         `json:"execution,omitempty"`
+	Offline         []tldr.Match       `json:"offline_suggestions,omitempty"` // from the local corpus when the API could not help
+	Error           *Error             `json:"error,omitempty"`
+}
+
+type Execution struct {
+	ExitCode   int    `json:"exit_code"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (r *Result) Fail(code string, format string, args ...interface{}) {
+	r.Error = &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (r *Result) Write(w io.Writer) error {
+	if r.Warnings == nil {
+		r.Warnings = []safety.Finding{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(r)
+}
@@ -0,0 +1,296 @@
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fl/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	Default = "default" // the profile used when none is selected
+	Env     = "FL_PROFILE"
+
+	// the config schema version, version 1 was the flat ~/.flconf
+	Version = 2
+
+	configFile      = "config.json"
+	profilesDir     = "profiles"
+	credentialsFile = "credentials"
+)
+
+// the config directory holds config.json with the schema version and the
+// profile in use, one settings file per profile and the encrypted credentials
+//
+//	$XDG_CONFIG_HOME/fl/config.json
+//	$XDG_CONFIG_HOME/fl/profiles/<name>.json
+//	$XDG_CONFIG_HOME/fl/credentials
+type Profiles struct {
+	dir     string
+	config  config
+	missing string // the current profile, when its file was removed
+}
+
+type config struct {
+	Version int    `json:"version"`
+	Profile string `json:"profile"`
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// $XDG_CONFIG_HOME/fl, else the platform's config directory
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "fl"), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fl"), nil
+}
+
+// open the config directory, creating it on first use and moving a legacy
+// config file (and the credentials next to it) into the default profile
+func Open(dir string, legacy string) (*Profiles, error) {
+	p := &Profiles{dir: dir, config: config{Version: Version, Profile: Default}}
+
+	raw, err := os.ReadFile(filepath.Join(dir, configFile))
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Join(dir, profilesDir), 0700); err != nil {
+			return nil, err
+		}
+		if err := p.migrateLegacy(legacy); err != nil {
+			return nil, err
+		}
+		return p, p.save()
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(raw, &p.config); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", filepath.Join(dir, configFile), err)
+	}
+	if p.config.Version > Version {
+		return nil, fmt.Errorf("%s was written by a newer version of fl (schema version %d, supported: %d)", filepath.Join(dir, configFile), p.config.Version, Version)
+	}
+	if p.config.Profile == "" {
+		p.config.Profile = Default
+	}
+	if p.config.Version < Version {
+		p.config.Version = Version
+		return p, p.save()
+	}
+	return p, nil
+}
+
+// move ~/.flconf and ~/.flcredentials into the config directory
+func (p *Profiles) migrateLegacy(legacy string) error {
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+
+	if err := upgrade(legacy, p.Path(Default)); err != nil {
+		return fmt.Errorf("moving %s to %s: %v", legacy, p.Path(Default), err)
+	}
+
+	oldCredentials := filepath.Join(filepath.Dir(legacy), ".flcredentials")
+	if _, err := os.Stat(oldCredentials); err == nil {
+		if err := move(oldCredentials, p.CredentialsFile()); err != nil {
+			return fmt.Errorf("moving %s to %s: %v", oldCredentials, p.CredentialsFile(), err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Moved the configuration from %s to %s\n", legacy, p.dir)
+	return nil
+}
+
+// rewrite a profile of an older schema version as the current version; the
+// keys of version 1 are unchanged so only the version is added
+func upgrade(src string, dst string) error {
+	raw, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	settings := map[string]interface{}{}
+	if len(strings.TrimSpace(string(raw))) > 0 {
+		if err := json.Unmarshal(raw, &settings); err != nil {
+			return err
+		}
+	}
+	settings["version"] = Version
+
+	out, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(dst, out, 0600); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// rename, or copy and remove when the files are on different devices
+func move(src string, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	raw, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(dst, raw, 0600); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+func (p *Profiles) save() error {
+	raw, err := json.MarshalIndent(p.config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(filepath.Join(p.dir, configFile), raw, 0600)
+}
+
+func (p *Profiles) Dir() string {
+	return p.dir
+}
+
+// the settings file of a profile
+func (p *Profiles) Path(name string) string {
+	return filepath.Join(p.dir, profilesDir, name+".json")
+}
+
+// the encrypted credentials file, shared by all profiles
+func (p *Profiles) CredentialsFile() string {
+	return filepath.Join(p.dir, credentialsFile)
+}
+
+// the profile chosen with fl profile use
+func (p *Profiles) Current() string {
+	return p.config.Profile
+}
+
+// the profile to run with: the --profile flag, else $FL_PROFILE, else the
+// current profile; if the file of the current profile was removed, the
+// default profile is used instead so that fl still runs
+func (p *Profiles) Select(flag string) (string, error) {
+	name := flag
+	if name == "" {
+		name = os.Getenv(Env)
+	}
+	if name == "" {
+		name = p.Current()
+		if !p.Exists(name) {
+			p.missing, p.config.Profile = name, Default
+			name = Default
+		}
+	}
+	if !p.Exists(name) {
+		return "", fmt.Errorf("profile '%s' does not exist (see fl profile list)", name)
+	}
+	return name, nil
+}
+
+// the current profile that Select replaced with the default one because its
+// file was removed, empty if there is none
+func (p *Profiles) Missing() string {
+	return p.missing
+}
+
+// the default profile always exists, even before anything is saved in it
+func (p *Profiles) Exists(name string) bool {
+	if name == Default {
+		return true
+	}
+	if !validName.MatchString(name) {
+		return false
+	}
+	_, err := os.Stat(p.Path(name))
+	return err == nil
+}
+
+// the profile names in alphabetical order
+func (p *Profiles) List() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(p.dir, profilesDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	names := []string{Default}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".json")
+		if !e.IsDir() && name != e.Name() && name != Default && validName.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// create a profile, with a copy of the settings of another profile if from is set
+func (p *Profiles) Create(name string, from string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s' (use letters, digits, '-' and '_')", name)
+	}
+	if p.Exists(name) {
+		return fmt.Errorf("profile '%s' already exists", name)
+	}
+
+	settings := []byte(fmt.Sprintf("{\n  \"version\": %d\n}", Version))
+	if from != "" {
+		if !p.Exists(from) {
+			return fmt.Errorf("profile '%s' does not exist", from)
+		}
+		raw, err := os.ReadFile(p.Path(from))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		} else if err == nil {
+			settings = raw
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.Path(name)), 0700); err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(p.Path(name), settings, 0600)
+}
+
+// make a profile the current one
+func (p *Profiles) Use(name string) error {
+	if !p.Exists(name) {
+		return fmt.Errorf("profile '%s' does not exist", name)
+	}
+	p.config.Profile = name
+	return p.save()
+}
+
+// delete a profile, neither the default nor the current profile can be deleted
+func (p *Profiles) Delete(name string) error {
+	if name == Default {
+		return fmt.Errorf("the default profile cannot be deleted")
+	}
+	if name == p.Current() {
+		return fmt.Errorf("profile '%s' is in use, switch to another profile first", name)
+	}
+	if !p.Exists(name) {
+		return fmt.Errorf("profile '%s' does not exist", name)
+	}
+	return os.Remove(p.Path(name))
+}
+
+// the credential store key of a profile's FLID, the default profile keeps
+// the key used before there were profiles
+func FLIDKey(name string) string {
+	if name == Default {
+		return "flid"
+	}
+	return "flid:" + name
+}
@@ -0,0 +1,69 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// test the profile is taken from the flag, then the environment, then the config
+func TestSelect(t *testing.T) {
+	t.Setenv(Env, "")
+	p, err := Open(t.TempDir(), filepath.Join(t.TempDir(), ".flconf"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	p.Create("work", "")
+	p.Create("home", "")
+	p.Use("home")
+
+	if name, _ := p.Select(""); name != "home" {
+		t.Fatalf(`Select("") = "%s", expected the current profile "home"`, name)
+	}
+	t.Setenv(Env, "work")
+	if name, _ := p.Select(""); name != "work" {
+		t.Fatalf(`Select("") with %s=work = "%s", expected "work"`, Env, name)
+	}
+	if name, _ := p.Select(Default); name != Default {
+		t.Fatalf(`Select("%s") = "%s", expected the flag to win`, Default, name)
+	}
+	if _, err := p.Select("missing"); err == nil {
+		t.Fatalf(`Select("missing") succeeded, expected an error`)
+	}
+	if err := p.Create("../escape", ""); err == nil {
+		t.Fatalf(`Create("../escape") succeeded, expected an invalid name error`)
+	}
+}
+
+// test a removed current profile falls back to the default one, but a
+// profile that is asked for must exist
+func TestSelectRemoved(t *testing.T) {
+	t.Setenv(Env, "")
+	p, err := Open(t.TempDir(), filepath.Join(t.TempDir(), ".flconf"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	p.Create("work", "")
+	p.Use("work")
+	os.Remove(p.Path("work"))
+
+	if name, err := p.Select(""); err != nil || name != Default || p.Missing() != "work" || p.Current() != Default {
+		t.Fatalf(`Select("") = ("%s", %v), missing "%s", expected the default profile in place of "work"`, name, err, p.Missing())
+	}
+	if _, err := p.Select("work"); err == nil {
+		t.Fatalf(`Select("work") succeeded, expected an error for the removed profile`)
+	}
+	t.Setenv(Env, "work")
+	if _, err := p.Select(""); err == nil {
+		t.Fatalf(`Select("") with %s=work succeeded, expected an error for the removed profile`, Env)
+	}
+}
+
+// test a config written by a newer fl is not misread
+func TestNewerVersion(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, configFile), []byte(`{"version": 99, "profile": "default"}`), 0600)
+	if _, err := Open(dir, ""); err == nil {
+		t.Fatalf("Open of schema version 99 succeeded, expected an error")
+	}
+}
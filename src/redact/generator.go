@@ -0,0 +1,71 @@
+package redact
+
+import (
+	"context"
+	"fl/api"
+)
+
+// a Generator that redacts secrets from everything it sends and restores
+// them in what comes back
+type Generator struct {
+	api.Generator
+	redactor *Redactor
+}
+
+func Wrap(gen api.Generator, redactor *Redactor) Generator {
+	return Generator{Generator: gen, redactor: redactor}
+}
+
+func (g Generator) Redactor() *Redactor {
+	return g.redactor
+}
+
+func (g Generator) Generate(ctx context.Context, req api.GenerateRequest) (*api.GeneratedCommandResult, error) {
+	r := g.redactor
+	req.Prompt = r.Redact(req.Prompt)
+	req.Context = r.Redact(req.Context)
+	req.Data = r.Redact(req.Data)
+
+	history := make([]api.Turn, len(req.History))
+	for i, t := range req.History {
+		history[i] = api.Turn{Prompt: r.Redact(t.Prompt), Cmd: r.Redact(t.Cmd)}
+	}
+	req.History = history
+
+	res, err := g.Generator.Generate(ctx, req)
+	if err != nil || res == nil {
+		return res, err
+	}
+
+	// the result may be cached, restore a copy
+	restored := *res
+	restored.Cmd = r.Restore(res.Cmd)
+	restored.Candidates = make([]api.Candidate, len(res.Candidates))
+	for i, c := range res.Candidates {
+		restored.Candidates[i] = api.Candidate{Cmd: r.Restore(c.Cmd), Description: r.Restore(c.Description)}
+	}
+	return &restored, nil
+}
+
+func (g Generator) Explain(ctx context.Context, cmd string, language string, flid string) (*api.ExplainResult, error) {
+	r := g.redactor
+	res, err := g.Generator.Explain(ctx, r.Redact(cmd), language, flid)
+	if err != nil || res == nil {
+		return res, err
+	}
+
+	restored := *res
+	restored.Summary = r.Restore(res.Summary)
+	restored.Stages = make([]api.ExplainedStage, len(res.Stages))
+	for i, s := range res.Stages {
+		stage := s
+		stage.Command = r.Restore(s.Command)
+		stage.Description = r.Restore(s.Description)
+		stage.Flags = make([]api.ExplainedFlag, len(s.Flags))
+		for j, f := range s.Flags {
+			stage.Flags[j] = api.ExplainedFlag{Flag: r.Restore(f.Flag), Description: r.Restore(f.Description)}
+		}
+		restored.Stages[i] = stage
+	}
+	return &restored, nil
+}
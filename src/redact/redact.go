@@ -0,0 +1,205 @@
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// secrets matched by a rule are replaced with a placeholder before a prompt
+// leaves the machine and put back into what the backend returns
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp // the secret is the first capture group if there is one, else the whole match
+	check   func(string) bool
+}
+
+// rules that are always applied
+var builtin = []Rule{
+	rule("private-key", `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	rule("aws-access-key", `\b((?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16})\b`),
+	rule("aws-secret-key", `(?i)aws_?secret_?access_?key\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+	rule("github-token", `\b((?:gh[pousr]_[A-Za-z0-9]{36,})|(?:github_pat_[A-Za-z0-9_]{22,}))\b`),
+	rule("slack-token", `\b(xox[abposr]-[A-Za-z0-9-]{10,})`),
+	rule("slack-webhook", `(https://hooks\.slack\.com/services/[A-Za-z0-9/]+)`),
+	rule("jwt", `\b(eyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,})`),
+	rule("url-password", `[A-Za-z][A-Za-z0-9+.-]*://[^\s/:@]+:([^\s/@]+)@`),
+	rule("password", `(?i)\b(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)\s*[=:]\s*["']?([^\s"']{4,})`),
+	{Name: "high-entropy", Pattern: regexp.MustCompile(`[A-Za-z0-9_+=-]{20,}`), check: highEntropy},
+}
+
+// rules that are only applied when enabled in the config
+var (
+	emailRule    = rule("email", `\b([A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,})\b`)
+	hostnameRule = rule("hostname", `\b((?:[A-Za-z0-9-]+\.)+(?:com|net|org|io|dev|app|cloud|ai|co|internal|local|lan|corp|intra|private))\b`)
+)
+
+func rule(name string, pattern string) Rule {
+	return Rule{Name: name, Pattern: regexp.MustCompile(pattern)}
+}
+
+// redaction settings read from the config file
+type Config struct {
+	Disabled  bool              // send prompts verbatim
+	Emails    bool              // also redact email addresses
+	Hostnames bool              // also redact host names
+	Patterns  map[string]string // custom rules, name -> regular expression
+}
+
+// a secret that was replaced with a placeholder
+type Redaction struct {
+	Rule        string `json:"rule"`
+	Placeholder string `json:"placeholder"`
+	Masked      string `json:"masked"` // enough of the secret to recognize it in audit views
+	Value       string `json:"-"`
+}
+
+func mask(secret string) string {
+	runes := []rune(secret)
+	if len(runes) <= 8 {
+		return strings.Repeat("*", len(runes))
+	}
+	return fmt.Sprintf("%s...%s (%d chars)", string(runes[:4]), string(runes[len(runes)-2:]), len(runes))
+}
+
+// replaces secrets with placeholders and restores them; the same secret
+// always gets the same placeholder so replies and the cache stay consistent
+type Redactor struct {
+	rules []Rule
+
+	mu         sync.Mutex
+	redactions []Redaction
+	bySecret   map[string]string // secret -> placeholder
+	counts     map[string]int    // rule -> placeholders issued
+}
+
+func New(conf Config) (*Redactor, error) {
+	r := &Redactor{bySecret: map[string]string{}, counts: map[string]int{}}
+	if conf.Disabled {
+		return r, nil
+	}
+
+	r.rules = append(r.rules, builtin...)
+	if conf.Emails {
+		r.rules = append(r.rules, emailRule)
+	}
+	if conf.Hostnames {
+		r.rules = append(r.rules, hostnameRule)
+	}
+
+	// custom rules run first so they can claim matches of the generic rules
+	names := make([]string, 0, len(conf.Patterns))
+	for name := range conf.Patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	custom := []Rule{}
+	for _, name := range names {
+		re, err := regexp.Compile(conf.Patterns[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern '%s': %v", name, err)
+		}
+		custom = append(custom, Rule{Name: name, Pattern: re})
+	}
+	r.rules = append(custom, r.rules...)
+
+	return r, nil
+}
+
+// the text with every secret replaced by its placeholder
+func (r *Redactor) Redact(text string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rl := range r.rules {
+		text = rl.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			secret := match
+			if rl.Pattern.NumSubexp() > 0 {
+				sub := rl.Pattern.FindStringSubmatch(match)
+				if sub == nil || sub[1] == "" {
+					return match
+				}
+				secret = sub[1]
+			}
+
+			// placeholders from earlier rules are not secrets
+			if isPlaceholder(secret) || (rl.check != nil && !rl.check(secret)) {
+				return match
+			}
+			return strings.Replace(match, secret, r.placeholder(rl.Name, secret), 1)
+		})
+	}
+	return text
+}
+
+// the text with placeholders replaced by the secrets they stand for
+func (r *Redactor) Restore(text string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// longer placeholders first so TOKEN_1 does not match inside TOKEN_10
+	redactions := append([]Redaction{}, r.redactions...)
+	sort.Slice(redactions, func(i, j int) bool {
+		return len(redactions[i].Placeholder) > len(redactions[j].Placeholder)
+	})
+	for _, red := range redactions {
+		text = strings.ReplaceAll(text, red.Placeholder, red.Value)
+	}
+	return text
+}
+
+// everything redacted so far, in the order it was found
+func (r *Redactor) Redactions() []Redaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Redaction{}, r.redactions...)
+}
+
+func (r *Redactor) placeholder(name string, secret string) string {
+	if p, ok := r.bySecret[secret]; ok {
+		return p
+	}
+
+	r.counts[name]++
+	p := fmt.Sprintf("<REDACTED_%s_%d>", strings.ToUpper(nonWord.ReplaceAllString(name, "_")), r.counts[name])
+	r.bySecret[secret] = p
+	r.redactions = append(r.redactions, Redaction{Rule: name, Placeholder: p, Masked: mask(secret), Value: secret})
+	return p
+}
+
+var (
+	placeholderPattern = regexp.MustCompile(`^<?REDACTED_[A-Z0-9_]+_\d+>?$`)
+	nonWord            = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+func isPlaceholder(s string) bool {
+	return placeholderPattern.MatchString(s)
+}
+
+// random looking tokens: mixed case letters and digits with high Shannon entropy
+func highEntropy(s string) bool {
+	var upper, lower, digit bool
+	freq := map[rune]float64{}
+	for _, c := range s {
+		upper = upper || unicode.IsUpper(c)
+		lower = lower || unicode.IsLower(c)
+		digit = digit || unicode.IsDigit(c)
+		freq[c]++
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+
+	entropy := 0.0
+	n := float64(len(s))
+	for _, f := range freq {
+		p := f / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= 4.0
+}
@@ -0,0 +1,170 @@
+package safety
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PowerShell cannot be parsed as a shell script, so its commands are split
+// into statements and words; quoting is only approximated, a ';' or '|' in a
+// string also ends a statement
+func analyzePowerShell(cmd string) Report {
+	report := Report{}
+	add := func(rule string, snippet string, format string, args ...interface{}) {
+		report.Findings = append(report.Findings, Finding{Rule: rule, Message: fmt.Sprintf(format, args...), Snippet: snippet})
+	}
+
+	for _, stmt := range psStatements(cmd) {
+		words := splitWords(stmt)
+		if len(words) == 0 {
+			continue
+		}
+		name := strings.ToLower(words[0])
+		flags, operands := psSplitParameters(words[1:])
+
+		switch {
+		case psRemove[name]:
+			if psHasParameter(flags, "recurse") {
+				for _, o := range operands {
+					if psBroadPath(o) {
+						add(RuleRecursiveRm, stmt, "recursively removes '%s'", o)
+					}
+				}
+			}
+
+		case psDisk[name]:
+			add(RuleDiskDevice, stmt, "%s modifies disks", words[0])
+
+		case psWrite[name]:
+			for _, o := range operands {
+				if psSensitivePath(o) {
+					add(RuleSensitiveWrite, stmt, "%s writes to '%s'", words[0], o)
+				}
+			}
+
+		case psCopy[name]:
+			if len(operands) > 0 && psSensitivePath(operands[len(operands)-1]) {
+				add(RuleSensitiveWrite, stmt, "%s writes to '%s'", words[0], operands[len(operands)-1])
+			}
+		}
+
+		// > and >> redirect the output of any command
+		for i, w := range words {
+			if (w == ">" || w == ">>") && i+1 < len(words) && psSensitivePath(words[i+1]) {
+				add(RuleSensitiveWrite, stmt, "writes to '%s'", words[i+1])
+			}
+		}
+	}
+
+	// iex (iwr https://...) and irm https://... | iex
+	if psInvoke.MatchString(cmd) && psDownload.MatchString(cmd) {
+		add(RulePipeToShell, cmd, "runs a downloaded script with Invoke-Expression")
+	}
+
+	return report
+}
+
+// cmdlets and their aliases, lowercase
+var (
+	psRemove = map[string]bool{"remove-item": true, "rm": true, "ri": true, "del": true, "erase": true, "rd": true, "rmdir": true}
+	psDisk   = map[string]bool{"format-volume": true, "clear-disk": true, "initialize-disk": true, "remove-partition": true}
+	psWrite  = map[string]bool{"set-content": true, "sc": true, "add-content": true, "ac": true, "out-file": true}
+	psCopy   = map[string]bool{"copy-item": true, "copy": true, "cp": true, "cpi": true, "move-item": true, "move": true, "mv": true, "mi": true}
+
+	psInvoke   = regexp.MustCompile(`(?i)\b(iex|invoke-expression)\b`)
+	psDownload = regexp.MustCompile(`(?i)\b(iwr|irm|invoke-webrequest|invoke-restmethod|downloadstring|downloadfile|curl|wget)\b`)
+)
+
+var psSeparators = strings.NewReplacer("&&", "\n", "||", "\n", ";", "\n", "|", "\n", "{", "\n", "}", "\n", "(", "\n", ")", "\n")
+
+// statements, including those in script blocks and subexpressions
+func psStatements(cmd string) []string {
+	statements := []string{}
+	for _, s := range strings.Split(psSeparators.Replace(cmd), "\n") {
+		if s = strings.TrimSpace(s); s != "" {
+			statements = append(statements, s)
+		}
+	}
+	return statements
+}
+
+// words of a statement without their quotes, redirections are words of their own
+func splitWords(stmt string) []string {
+	words := []string{}
+	for _, w := range strings.Fields(stmt) {
+		if strings.HasPrefix(w, ">") && w != ">" && w != ">>" {
+			op := ">"
+			if strings.HasPrefix(w, ">>") {
+				op = ">>"
+			}
+			words = append(words, op, strings.Trim(w[len(op):], `'"`))
+			continue
+		}
+		words = append(words, strings.Trim(w, `'"`))
+	}
+	return words
+}
+
+// parameters whose value is not a path
+var psPatternParameters = map[string]bool{"-include": true, "-exclude": true, "-filter": true, "-encoding": true, "-value": true}
+
+// split parameters from the other words, the value of a pattern parameter is
+// skipped
+func psSplitParameters(words []string) (parameters []string, operands []string) {
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if w == ">" || w == ">>" {
+			i++
+			continue
+		}
+		if strings.HasPrefix(w, "-") && len(w) > 1 {
+			parameters = append(parameters, strings.ToLower(w))
+			if psPatternParameters[strings.ToLower(w)] {
+				i++
+			}
+			continue
+		}
+		operands = append(operands, w)
+	}
+	return parameters, operands
+}
+
+// PowerShell accepts any unambiguous prefix of a parameter name, e.g. -r or -rec for -Recurse
+func psHasParameter(parameters []string, name string) bool {
+	for _, p := range parameters {
+		p = strings.TrimSuffix(strings.TrimPrefix(p, "-"), ":$true")
+		if p != "" && strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	psHome  = regexp.MustCompile(`(?i)^(\$env:userprofile|\$env:home|\$home|\$\{home\})`)
+	psDrive = regexp.MustCompile(`(?i)^[a-z]:`)
+)
+
+// a path with home variables as ~, without its drive letter and with forward slashes
+func psNormalize(p string) string {
+	p = strings.ReplaceAll(p, `\`, "/")
+	p = psHome.ReplaceAllString(p, "~")
+	return psDrive.ReplaceAllString(p, "")
+}
+
+// a drive root, the home directory, a top level directory, or everything in one of them
+func psBroadPath(p string) bool {
+	if strings.EqualFold(p, "$env:systemroot") || strings.EqualFold(p, "$env:windir") {
+		return true
+	}
+	return broadPath(psNormalize(p))
+}
+
+// the PowerShell profile, Windows system files, and the files sensitivePath covers
+func psSensitivePath(p string) bool {
+	lower := strings.ToLower(psNormalize(p))
+	return strings.HasPrefix(lower, "$profile") || strings.HasPrefix(lower, "/windows/") ||
+		strings.HasPrefix(lower, "$env:systemroot") || strings.HasPrefix(lower, "$env:windir") ||
+		sensitivePath(psNormalize(p))
+}
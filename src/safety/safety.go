@@ -0,0 +1,598 @@
+package safety
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// rule identifiers of findings
+const (
+	RuleUnparsed       = "unparsed"
+	RuleRecursiveRm    = "recursive-rm"
+	RuleDiskDevice     = "disk-device"
+	RuleChmod777       = "chmod-777"
+	RulePipeToShell    = "pipe-to-shell"
+	RuleForkBomb       = "fork-bomb"
+	RuleSensitiveWrite = "sensitive-write"
+)
+
+// what the user must type to run a command with findings
+const ConfirmPhrase = "run anyway"
+
+// a destructive pattern found in a command
+type Finding struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Snippet string `json:"snippet"` // the part of the command that triggered the rule
+}
+
+type Report struct {
+	Findings []Finding
+	Notices  []string // limits of the check that do not block the command
+}
+
+// commands with findings must never run without an explicit confirmation
+func (r Report) Dangerous() bool {
+	return len(r.Findings) > 0
+}
+
+func (r Report) String() string {
+	text := ""
+	if r.Dangerous() {
+		text = "Warning: this command may be destructive:\n"
+		for _, f := range r.Findings {
+			text += fmt.Sprintf("  - %s (%s)\n", f.Message, f.Rule)
+		}
+	}
+	for _, n := range r.Notices {
+		text += fmt.Sprintf("Note: %s\n", n)
+	}
+	return text
+}
+
+// parse the command as the langtool's shell and walk its syntax tree looking
+// for destructive patterns, a command that cannot be parsed cannot be checked
+// and is reported as such; PowerShell has rules of its own
+func Analyze(cmd string, langtool string) Report {
+	if langtool == "powershell" {
+		return analyzePowerShell(cmd)
+	}
+
+	report := Report{}
+	report.analyze(cmd, langtool)
+	return report
+}
+
+// check a script of the given shell, scripts passed to bash -c or eval are
+// checked the same way
+func (r *Report) analyze(script string, shell string) {
+	variant := syntax.LangBash
+	switch shell {
+	case "sh", "dash":
+		variant = syntax.LangPOSIX
+	case "zsh", "fish":
+		// zsh and fish one-liners are mostly valid bash, what bash cannot
+		// parse, such as glob qualifiers or fish blocks, is checked word by word
+		if err := r.walk(script, variant); err != nil {
+			r.checkWords(script)
+			r.Notices = append(r.Notices, fmt.Sprintf("%s syntax is only partly understood, the command was checked word by word", shell))
+		}
+		return
+	}
+
+	if err := r.walk(script, variant); err != nil {
+		r.add(RuleUnparsed, script, "the command could not be checked for destructive patterns: %v", err)
+	}
+}
+
+func (r *Report) walk(script string, variant syntax.LangVariant) error {
+	file, err := syntax.NewParser(syntax.Variant(variant)).Parse(strings.NewReader(script), "")
+	if err != nil {
+		return err
+	}
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.CallExpr:
+			r.checkCall(n)
+		case *syntax.Redirect:
+			r.checkRedirect(n)
+		case *syntax.BinaryCmd:
+			r.checkPipe(n)
+		case *syntax.FuncDecl:
+			r.checkForkBomb(n)
+		}
+		return true
+	})
+	return nil
+}
+
+func (r *Report) add(rule string, snippet string, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, Finding{Rule: rule, Message: fmt.Sprintf(format, args...), Snippet: snippet})
+}
+
+// a command that runs the command that follows its options
+type wrapper struct {
+	values   map[string]bool // options that take the next word as their value
+	operands int             // operands before the command, e.g. the duration of timeout
+}
+
+func options(names string) map[string]bool {
+	set := map[string]bool{}
+	for _, n := range strings.Fields(names) {
+		set[n] = true
+	}
+	return set
+}
+
+var wrappers = map[string]wrapper{
+	"sudo":    {values: options("-u -g -C -D -p -r -t -T -U --user --group --close-from --chdir --prompt --role --type --command-timeout --other-user")},
+	"doas":    {values: options("-u -C")},
+	"env":     {values: options("-u -C --unset --chdir")},
+	"nohup":   {},
+	"time":    {values: options("-f -o --format --output")},
+	"nice":    {values: options("-n --adjustment")},
+	"ionice":  {values: options("-c -n -p -P -u --class --classdata --pid --pgid --uid")},
+	"exec":    {values: options("-a")},
+	"command": {},
+	"xargs":   {values: options("-a -d -E -I -L -n -P -s --arg-file --delimiter --max-lines --max-args --max-procs --max-chars --process-slot-var")},
+	"timeout": {values: options("-s -k --signal --kill-after"), operands: 1},
+	"stdbuf":  {values: options("-i -o -e --input --output --error")},
+}
+
+// the command name and arguments, without wrappers such as sudo or xargs
+func callArgs(call *syntax.CallExpr) (string, []string) {
+	args := make([]string, 0, len(call.Args))
+	for _, w := range call.Args {
+		args = append(args, wordText(w))
+	}
+	return unwrap(args)
+}
+
+func unwrap(args []string) (string, []string) {
+	for len(args) > 0 {
+		w, ok := wrappers[path.Base(args[0])]
+		if !ok {
+			break
+		}
+
+		args = args[1:]
+		for len(args) > 0 && (strings.HasPrefix(args[0], "-") || strings.Contains(args[0], "=")) {
+			if args[0] == "--" {
+				args = args[1:]
+				break
+			}
+			if w.values[args[0]] && len(args) > 1 {
+				args = args[1:]
+			}
+			args = args[1:]
+		}
+		for i := 0; i < w.operands && len(args) > 0; i++ {
+			args = args[1:]
+		}
+	}
+
+	if len(args) == 0 {
+		return "", nil
+	}
+	return path.Base(args[0]), args[1:]
+}
+
+var watchValues = options("-n -q --interval --equexit")
+
+// the script run by bash -c, eval or watch
+func script(name string, args []string) (string, bool) {
+	switch name {
+	case "eval":
+		return strings.Join(args, " "), len(args) > 0
+
+	case "watch":
+		// watch joins its operands and runs them with sh -c
+		i := 0
+		for i < len(args) && strings.HasPrefix(args[i], "-") {
+			if args[i] == "--" {
+				i++
+				break
+			}
+			if watchValues[args[i]] {
+				i++
+			}
+			i++
+		}
+		if i >= len(args) {
+			return "", false
+		}
+		return strings.Join(args[i:], " "), true
+	}
+
+	switch name {
+	case "sh", "bash", "zsh", "dash", "ksh", "fish":
+	default:
+		return "", false
+	}
+
+	// the first operand after -c, or after a group of options with c such as -lc
+	command := false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "-o" || a == "+o" || a == "-O" || a == "+O":
+			i++
+		case strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "--"):
+			command = command || strings.Contains(a, "c")
+		case strings.HasPrefix(a, "--"):
+		default:
+			return a, command
+		}
+	}
+	return "", false
+}
+
+func (r *Report) checkCall(call *syntax.CallExpr) {
+	args := make([]string, 0, len(call.Args))
+	for _, w := range call.Args {
+		args = append(args, wordText(w))
+	}
+	r.checkArgs(args, source(call))
+
+	// sh -c "$(curl ...)" and bash <(curl ...)
+	if name, _ := unwrap(args); shells[name] {
+		for _, w := range call.Args {
+			if downloads(w) {
+				r.add(RulePipeToShell, source(call), "runs a downloaded script with %s", name)
+				break
+			}
+		}
+	}
+}
+
+// check the words of one command, snippet is the command as written
+func (r *Report) checkArgs(words []string, snippet string) {
+	name, args := unwrap(words)
+	flags, operands := splitFlags(args)
+
+	switch {
+	case name == "rm":
+		if hasFlag(flags, "r", "R", "recursive") {
+			for _, o := range operands {
+				if broadPath(o) {
+					r.add(RuleRecursiveRm, snippet, "recursively removes '%s'", o)
+				}
+			}
+		}
+
+	case name == "find":
+		if findDeletes(args) {
+			paths, narrowed := findPaths(args)
+			for _, p := range paths {
+				if broadPath(p) && !(narrowed && currentDir(p)) {
+					r.add(RuleRecursiveRm, snippet, "find deletes files under '%s'", p)
+				}
+			}
+		}
+
+	case name == "dd":
+		for _, a := range args {
+			if strings.HasPrefix(a, "of=") && devicePath(strings.TrimPrefix(a, "of=")) {
+				r.add(RuleDiskDevice, snippet, "dd overwrites the device '%s'", strings.TrimPrefix(a, "of="))
+			}
+		}
+
+	case strings.HasPrefix(name, "mkfs") || name == "mkswap" || name == "wipefs" || name == "fdisk" ||
+		name == "sfdisk" || name == "parted" || name == "shred" || name == "blkdiscard":
+		for _, o := range operands {
+			if devicePath(o) {
+				r.add(RuleDiskDevice, snippet, "%s modifies the device '%s'", name, o)
+			}
+		}
+
+	case name == "chmod":
+		if len(operands) > 0 && worldWritable(operands[0]) {
+			if hasFlag(flags, "R", "recursive") {
+				r.add(RuleChmod777, snippet, "recursively makes files world writable")
+			} else {
+				for _, o := range operands[1:] {
+					if broadPath(o) {
+						r.add(RuleChmod777, snippet, "makes '%s' world writable", o)
+					}
+				}
+			}
+		}
+
+	case name == "tee" || name == "cp" || name == "mv" || name == "install" || name == "ln":
+		targets := operands
+		if name != "tee" && len(operands) > 0 {
+			targets = operands[len(operands)-1:]
+		}
+		for _, t := range targets {
+			if sensitivePath(t) {
+				r.add(RuleSensitiveWrite, snippet, "%s writes to '%s'", name, t)
+			}
+		}
+
+	case name == "sed" || name == "perl":
+		if hasFlag(flags, "i", "in-place") {
+			for _, o := range operands {
+				if sensitivePath(o) {
+					r.add(RuleSensitiveWrite, snippet, "%s edits '%s' in place", name, o)
+				}
+			}
+		}
+	}
+
+	// the scripts of bash -c '...' and eval '...' are checked like the command
+	if text, ok := script(name, args); ok {
+		r.analyze(text, name)
+	}
+}
+
+func (r *Report) checkRedirect(redirect *syntax.Redirect) {
+	switch redirect.Op {
+	case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll:
+	default:
+		return
+	}
+
+	// the printer cannot print a redirect on its own
+	snippet := fmt.Sprintf("%s %s", redirect.Op, source(redirect.Word))
+	if redirect.N != nil {
+		snippet = redirect.N.Value + snippet
+	}
+	r.checkTarget(wordText(redirect.Word), snippet)
+}
+
+// output redirected to a device or a sensitive file
+func (r *Report) checkTarget(target string, snippet string) {
+	if devicePath(target) {
+		r.add(RuleDiskDevice, snippet, "writes directly to the device '%s'", target)
+	} else if sensitivePath(target) {
+		r.add(RuleSensitiveWrite, snippet, "writes to '%s'", target)
+	}
+}
+
+var wordSeparators = strings.NewReplacer("&&", "\n", "||", "\n", ";", "\n", "&", "\n", "(", "\n", ")", "\n", "{", "\n", "}", "\n", "`", "\n")
+
+// keywords of zsh and fish that come before a command
+var keywords = map[string]bool{"and": true, "or": true, "not": true, "!": true, "if": true, "else": true, "while": true, "begin": true, "then": true, "do": true}
+
+// what bash cannot parse is split into pipelines, commands and words as for
+// PowerShell; quoting is only approximated
+func (r *Report) checkWords(script string) {
+	for _, pipeline := range strings.Split(wordSeparators.Replace(script), "\n") {
+		downloaded := false
+		for _, stmt := range strings.Split(pipeline, "|") {
+			stmt = strings.TrimSpace(stmt)
+			words, args := splitWords(stmt), []string{}
+			for i := 0; i < len(words); i++ {
+				if words[i] == ">" || words[i] == ">>" {
+					if i+1 < len(words) {
+						r.checkTarget(words[i+1], words[i]+" "+words[i+1])
+					}
+					i++
+					continue
+				}
+				args = append(args, words[i])
+			}
+			for len(args) > 0 && keywords[args[0]] {
+				args = args[1:]
+			}
+			if len(args) == 0 {
+				continue
+			}
+
+			r.checkArgs(args, stmt)
+			name, _ := unwrap(args)
+			if shells[name] && downloaded {
+				r.add(RulePipeToShell, strings.TrimSpace(pipeline), "pipes a downloaded script into %s", name)
+			}
+			downloaded = downloaded || downloaders[name]
+		}
+	}
+}
+
+// interpreters that execute scripts read from stdin or passed as arguments
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true,
+	"python": true, "python3": true, "perl": true, "ruby": true, "node": true, "php": true,
+}
+
+var downloaders = map[string]bool{"curl": true, "wget": true, "fetch": true, "aria2c": true}
+
+func (r *Report) checkPipe(bin *syntax.BinaryCmd) {
+	if bin.Op != syntax.Pipe && bin.Op != syntax.PipeAll {
+		return
+	}
+
+	call, ok := bin.Y.Cmd.(*syntax.CallExpr)
+	if !ok {
+		return
+	}
+
+	if name, _ := callArgs(call); shells[name] && downloads(bin.X) {
+		r.add(RulePipeToShell, source(bin), "pipes a downloaded script into %s", name)
+	}
+}
+
+// whether the node runs curl, wget or similar
+func downloads(node syntax.Node) bool {
+	found := false
+	syntax.Walk(node, func(n syntax.Node) bool {
+		if call, ok := n.(*syntax.CallExpr); ok {
+			if name, _ := callArgs(call); downloaders[name] {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// a function that calls itself in a pipeline or in the background, e.g. :(){ :|:& };:
+func (r *Report) checkForkBomb(fn *syntax.FuncDecl) {
+	name := fn.Name.Value
+	calls, spawns := 0, false
+
+	syntax.Walk(fn.Body, func(n syntax.Node) bool {
+		switch x := n.(type) {
+		case *syntax.CallExpr:
+			if callee, _ := callArgs(x); callee == name {
+				calls++
+			}
+		case *syntax.Stmt:
+			if x.Background {
+				spawns = true
+			}
+		case *syntax.BinaryCmd:
+			if x.Op == syntax.Pipe || x.Op == syntax.PipeAll {
+				spawns = true
+			}
+		}
+		return true
+	})
+
+	if calls > 0 && spawns {
+		r.add(RuleForkBomb, source(fn), "the function '%s' spawns copies of itself without limit", name)
+	}
+}
+
+// whether a find expression deletes what it finds, with -delete or by running rm
+func findDeletes(args []string) bool {
+	for i, a := range args {
+		switch a {
+		case "-delete":
+			return true
+		case "-exec", "-execdir", "-ok", "-okdir":
+			if name, _ := unwrap(args[i+1:]); name == "rm" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// the starting points of find, "." if there are none, and whether a test on
+// the names of the files narrows what is found
+func findPaths(args []string) (paths []string, narrowed bool) {
+	i := 0
+	for i < len(args) && (args[i] == "-H" || args[i] == "-L" || args[i] == "-P" || strings.HasPrefix(args[i], "-O") || args[i] == "-D") {
+		if args[i] == "-D" {
+			i++
+		}
+		i++
+	}
+	for ; i < len(args) && !strings.HasPrefix(args[i], "-") && args[i] != "(" && args[i] != "!"; i++ {
+		paths = append(paths, args[i])
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	for _, a := range args[i:] {
+		switch a {
+		case "-name", "-iname", "-path", "-ipath", "-wholename", "-iwholename", "-regex", "-iregex":
+			narrowed = true
+		}
+	}
+	return paths, narrowed
+}
+
+func currentDir(p string) bool {
+	p = strings.TrimRight(p, "/")
+	return p == "." || p == ".."
+}
+
+// split options from operands, "--" ends the options
+func splitFlags(args []string) (flags []string, operands []string) {
+	for i, a := range args {
+		if a == "--" {
+			return flags, append(operands, args[i+1:]...)
+		}
+		if strings.HasPrefix(a, "-") && len(a) > 1 {
+			flags = append(flags, a)
+		} else {
+			operands = append(operands, a)
+		}
+	}
+	return flags, operands
+}
+
+// whether any short (combined, e.g. -rf) or long flag is set
+func hasFlag(flags []string, names ...string) bool {
+	for _, f := range flags {
+		for _, n := range names {
+			if len(n) == 1 && !strings.HasPrefix(f, "--") && strings.Contains(f[1:], n) {
+				return true
+			}
+			if len(n) > 1 && (f == "--"+n || strings.HasPrefix(f, "--"+n+"=")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var topLevel = regexp.MustCompile(`^/[^/]*/?\*?$`)
+
+// the root, home or current directory, a top level directory, or everything in one of them
+func broadPath(p string) bool {
+	p = strings.TrimRight(p, "/")
+	switch p {
+	case "", "*", ".", "..", "./*", "../*", "~", "~/*", "$HOME", "${HOME}", "$HOME/*", "${HOME}/*", "/*":
+		return true
+	}
+	return topLevel.MatchString(p)
+}
+
+var devices = regexp.MustCompile(`^/dev/(sd|hd|vd|xvd|nvme|mmcblk|disk|rdisk|md|dm-|loop|mapper/)`)
+
+func devicePath(p string) bool {
+	return devices.MatchString(p)
+}
+
+var worldWritableMode = regexp.MustCompile(`^(0?777|[ugoa]*\+rwx|a=rwx|ugo=rwx|o\+w)$`)
+
+func worldWritable(mode string) bool {
+	return worldWritableMode.MatchString(mode)
+}
+
+var sensitive = regexp.MustCompile(`^(/etc(/|$)|(~[^/]*|\$HOME|\$\{HOME\}|/home/[^/]+|/Users/[^/]+|/root)/\.[^/]+)`)
+
+// system configuration in /etc or dotfiles in a home directory
+func sensitivePath(p string) bool {
+	return sensitive.MatchString(p)
+}
+
+// approximate text of a word: quotes removed, expansions kept as written
+func wordText(w *syntax.Word) string {
+	if w == nil {
+		return ""
+	}
+	if lit := w.Lit(); lit != "" {
+		return lit
+	}
+
+	var sb strings.Builder
+	for _, part := range w.Parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			sb.WriteString(p.Value)
+		case *syntax.SglQuoted:
+			sb.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			sb.WriteString(wordText(&syntax.Word{Parts: p.Parts}))
+		default:
+			sb.WriteString(source(part))
+		}
+	}
+	return sb.String()
+}
+
+func source(node syntax.Node) string {
+	var sb strings.Builder
+	syntax.NewPrinter(syntax.Minify(true)).Print(&sb, node)
+	return strings.TrimSpace(sb.String())
+}
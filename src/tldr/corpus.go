@@ -0,0 +1,220 @@
+package tldr
+
+import (
+	"embed"
+	"errors"
+	"fl/utils"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// a starter set of pages so fl can help without a network
+//
+//go:embed pages
+var bundled embed.FS
+
+// directories of the tldr-pages repository, one per platform
+var platforms = []string{"common", "linux", "osx", "windows", "freebsd", "netbsd", "openbsd", "sunos", "android"}
+
+// the tldr platform of the running system
+func Platform() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "osx"
+	case "linux", "windows", "freebsd", "netbsd", "openbsd", "android":
+		return runtime.GOOS
+	case "solaris", "illumos":
+		return "sunos"
+	}
+	return "common"
+}
+
+// the pages of the common platform and the running one, searchable by prompt
+type Corpus struct {
+	pages map[string]*Page
+	index *index
+}
+
+// load the bundled pages and those imported into dir, which replace the
+// bundled page of the same name; pages for the running platform replace
+// common ones
+func Load(dir string) (*Corpus, error) {
+	c := &Corpus{pages: map[string]*Page{}}
+	sources := []fs.FS{}
+	if sub, err := fs.Sub(bundled, "pages"); err == nil {
+		sources = append(sources, sub)
+	}
+	if dir != "" {
+		sources = append(sources, os.DirFS(dir))
+	}
+
+	for _, source := range sources {
+		for _, platform := range []string{"common", Platform()} {
+			if err := c.load(source, platform); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	c.index = newIndex(c.Pages())
+	return c, nil
+}
+
+func (c *Corpus) load(source fs.FS, platform string) error {
+	files, err := fs.ReadDir(source, platform)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() || path.Ext(f.Name()) != ".md" {
+			continue
+		}
+		text, err := fs.ReadFile(source, path.Join(platform, f.Name()))
+		if err != nil {
+			return err
+		}
+		page, err := Parse(strings.TrimSuffix(f.Name(), ".md"), platform, string(text))
+		if err != nil {
+			continue
+		}
+		c.pages[page.Name] = page
+	}
+	return nil
+}
+
+// the page of a tool, nil if there is none
+func (c *Corpus) Page(name string) *Page {
+	return c.pages[pageName(name)]
+}
+
+// all pages by name
+func (c *Corpus) Pages() []*Page {
+	pages := make([]*Page, 0, len(c.pages))
+	for _, p := range c.pages {
+		pages = append(pages, p)
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Name < pages[j].Name
+	})
+	return pages
+}
+
+// names of pages that are spelled like name, closest first
+func (c *Corpus) Similar(name string, n int) []string {
+	name = pageName(name)
+	allowed := 1
+	if len(name) > 4 {
+		allowed = 2
+	}
+	type candidate struct {
+		name     string
+		distance int
+	}
+	candidates := []candidate{}
+	for other := range c.pages {
+		d := distance(name, other)
+		if strings.HasPrefix(other, name+"-") {
+			d = 1
+		}
+		if d <= allowed {
+			candidates = append(candidates, candidate{other, d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	names := []string{}
+	for i := 0; i < len(candidates) && i < n; i++ {
+		names = append(names, candidates[i].name)
+	}
+	return names
+}
+
+// copy the pages of a tldr-pages checkout, a directory of pages or a single
+// page into dir, where Load finds them; translations are not imported;
+// returns the number of pages imported
+func Import(src string, dir string) (int, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return 0, err
+	}
+	if info.IsDir() {
+		if pages := filepath.Join(src, "pages"); isDir(pages) {
+			src = pages
+		}
+	}
+
+	imported := 0
+	err = filepath.WalkDir(src, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// translations are in sibling directories such as pages.de
+			if file != src && strings.HasPrefix(d.Name(), "pages.") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(file) != ".md" {
+			return nil
+		}
+
+		platform := filepath.Base(filepath.Dir(file))
+		if !known(platform) {
+			platform = "common"
+		}
+
+		text, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		page, err := Parse(strings.TrimSuffix(d.Name(), ".md"), platform, string(text))
+		if err != nil {
+			return nil
+		}
+
+		if err = os.MkdirAll(filepath.Join(dir, platform), 0700); err != nil {
+			return err
+		}
+		if err = utils.WriteFileAtomic(filepath.Join(dir, platform, page.Name+".md"), text, 0600); err != nil {
+			return err
+		}
+		imported++
+		return nil
+	})
+	if err != nil {
+		return imported, err
+	}
+	if imported == 0 {
+		return 0, fmt.Errorf("no tldr pages found in %s", src)
+	}
+	return imported, nil
+}
+
+func known(platform string) bool {
+	for _, p := range platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,211 @@
+package tldr
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters, the usual defaults
+const (
+	k1 = 1.2
+	b  = 0.75
+
+	// a query word matching a word of the corpus only approximately counts this much
+	fuzzyWeight = 0.6
+)
+
+// an example of a page that matches a prompt
+type Match struct {
+	Page        string  `json:"page"`
+	Description string  `json:"description"`
+	Command     string  `json:"command"` // placeholders are shown as <placeholder>
+	Score       float64 `json:"score"`
+}
+
+// each example is a document made of its description, its command and the
+// name and description of its page
+type document struct {
+	page    *Page
+	example Example
+	terms   map[string]int
+	length  int
+}
+
+type index struct {
+	docs      []document
+	frequency map[string]int // documents containing each term
+	avgLength float64
+}
+
+func newIndex(pages []*Page) *index {
+	idx := &index{frequency: map[string]int{}}
+	total := 0
+	for _, p := range pages {
+		for _, e := range p.Examples {
+			words := terms(e.Description + " " + placeholder.ReplaceAllString(e.Command, " ") + " " + p.Name + " " + p.Name + " " + p.Description)
+			doc := document{page: p, example: e, terms: map[string]int{}, length: len(words)}
+			for _, w := range words {
+				doc.terms[w]++
+			}
+			for t := range doc.terms {
+				idx.frequency[t]++
+			}
+			idx.docs = append(idx.docs, doc)
+			total += doc.length
+		}
+	}
+	if len(idx.docs) > 0 {
+		idx.avgLength = float64(total) / float64(len(idx.docs))
+	}
+	return idx
+}
+
+// the examples that best match the query, at most n
+func (c *Corpus) Search(query string, n int) []Match {
+	idx := c.index
+	weights := map[string]float64{}
+	for _, q := range terms(query) {
+		for t, w := range idx.expand(q) {
+			weights[t] = math.Max(weights[t], w)
+		}
+	}
+
+	matches := []Match{}
+	for _, doc := range idx.docs {
+		score := 0.0
+		for t, w := range weights {
+			tf := float64(doc.terms[t])
+			if tf == 0 {
+				continue
+			}
+			norm := tf * (k1 + 1) / (tf + k1*(1-b+b*float64(doc.length)/idx.avgLength))
+			score += w * idx.idf(t) * norm
+		}
+		if score > 0 {
+			matches = append(matches, Match{Page: doc.page.Name, Description: doc.example.Description, Command: doc.example.Cmd(), Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > n {
+		matches = matches[:n]
+	}
+	return matches
+}
+
+func (idx *index) idf(term string) float64 {
+	n := float64(len(idx.docs))
+	df := float64(idx.frequency[term])
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+// words of prompts and the word the pages use for them
+var synonyms = map[string]string{
+	"folder":     "directory",
+	"dir":        "directory",
+	"erase":      "remove",
+	"delete":     "remove",
+	"unpack":     "extract",
+	"decompress": "extract",
+	"uncompress": "extract",
+	"untar":      "extract",
+	"rename":     "move",
+	"size":       "usage",
+	"space":      "usage",
+	"fetch":      "download",
+}
+
+// the corpus terms a query term stands for: itself and its synonym, or
+// words one or two edits away for misspellings, and longer words it is a
+// prefix of
+func (idx *index) expand(q string) map[string]float64 {
+	expanded := map[string]float64{}
+	if synonym, ok := synonyms[q]; ok {
+		expanded[stem(synonym)] = fuzzyWeight
+	}
+	if _, ok := idx.frequency[q]; ok {
+		expanded[q] = 1
+		return expanded
+	}
+
+	if len(q) < 4 {
+		return expanded
+	}
+	allowed := 1
+	if len(q) >= 8 {
+		allowed = 2
+	}
+	for t := range idx.frequency {
+		if strings.HasPrefix(t, q) || distance(q, t) <= allowed {
+			expanded[t] = fuzzyWeight
+		}
+	}
+	return expanded
+}
+
+// words that say nothing about the command
+var stopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "to": true, "of": true, "in": true, "into": true, "on": true,
+	"for": true, "with": true, "and": true, "or": true, "by": true, "from": true, "at": true, "as": true,
+	"all": true, "my": true, "me": true, "i": true, "it": true, "its": true, "is": true, "are": true,
+	"be": true, "how": true, "do": true, "does": true, "that": true, "this": true, "these": true,
+	"using": true, "use": true, "can": true, "what": true, "which": true, "some": true, "any": true,
+	"please": true, "show": true, "command": true,
+}
+
+// lowercase words without stopwords, reduced to a common stem
+func terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	result := []string{}
+	for _, w := range words {
+		if stopwords[w] {
+			continue
+		}
+		result = append(result, stem(w))
+	}
+	return result
+}
+
+// strip common English suffixes so "files", "listing" and "listed" match "file" and "list"
+func stem(w string) string {
+	switch {
+	case len(w) > 4 && strings.HasSuffix(w, "ies"):
+		return w[:len(w)-3] + "y"
+	case len(w) > 5 && strings.HasSuffix(w, "ing"):
+		return w[:len(w)-3]
+	case len(w) > 4 && strings.HasSuffix(w, "ed"):
+		return w[:len(w)-2]
+	case len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+// Levenshtein distance of two words
+func distance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
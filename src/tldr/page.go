@@ -0,0 +1,87 @@
+package tldr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// a cheat sheet for one tool in the tldr-pages format:
+//
+//	# tar
+//
+//	> Archiving utility.
+//	> More information: <https://www.gnu.org/software/tar>.
+//
+//	- Create an archive from files:
+//
+//	`tar cf {{target.tar}} {{file1 file2 ...}}`
+type Page struct {
+	Name        string
+	Platform    string // common, linux, osx, windows, ...
+	Description string
+	Examples    []Example
+}
+
+type Example struct {
+	Description string
+	Command     string // with tldr {{placeholders}}
+}
+
+var placeholder = regexp.MustCompile(`\{\{(.*?)\}\}`)
+
+// the command with its placeholders shown as <placeholder>, so it cannot be
+// mistaken for a command that is ready to run
+func (e Example) Cmd() string {
+	return placeholder.ReplaceAllString(e.Command, "<$1>")
+}
+
+// parse a page; name is used when the page has no title
+func Parse(name string, platform string, text string) (*Page, error) {
+	page := &Page{Name: name, Platform: platform}
+	description := []string{}
+	var example *Example
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "# "):
+			page.Name = pageName(strings.TrimSpace(line[2:]))
+		case strings.HasPrefix(line, ">"):
+			text := strings.TrimSpace(line[1:])
+			if !strings.HasPrefix(text, "More information:") && !strings.HasPrefix(text, "See also:") {
+				description = append(description, text)
+			}
+		case strings.HasPrefix(line, "- "):
+			example = &Example{Description: strings.TrimSuffix(strings.TrimSpace(line[2:]), ":")}
+		case len(line) > 1 && strings.HasPrefix(line, "`") && strings.HasSuffix(line, "`") && example != nil:
+			example.Command = line[1 : len(line)-1]
+			page.Examples = append(page.Examples, *example)
+			example = nil
+		}
+	}
+
+	if page.Name == "" || len(page.Examples) == 0 {
+		return nil, fmt.Errorf("%s is not a tldr page", name)
+	}
+	page.Description = strings.Join(description, " ")
+	return page, nil
+}
+
+// page names are lowercase with dashes, e.g. "git commit" is git-commit
+func pageName(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), "-"))
+}
+
+// the page as plain text for a terminal
+func (p *Page) String() string {
+	var b strings.Builder
+	b.WriteString(p.Name + "\n")
+	if p.Description != "" {
+		b.WriteString(p.Description + "\n")
+	}
+	for _, e := range p.Examples {
+		fmt.Fprintf(&b, "\n  %s:\n      %s\n", e.Description, e.Cmd())
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package tldr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const page = `# git commit
+
+> Commit files to the repository.
+> More information: <https://git-scm.com/docs/git-commit>.
+
+- Commit staged files with a message:
+
+` + "`git commit --message \"{{message}}\"`" + `
+
+- Amend the last commit:
+
+` + "`git commit --amend`\n"
+
+// test pages in the tldr format are parsed and placeholders are marked
+func TestParse(t *testing.T) {
+	p, err := Parse("x", "common", page)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if p.Name != "git-commit" || p.Description != "Commit files to the repository." || len(p.Examples) != 2 {
+		t.Fatalf(`Parse = (%s, "%s", %d examples), expected (git-commit, "Commit files to the repository.", 2 examples)`, p.Name, p.Description, len(p.Examples))
+	}
+	if cmd := p.Examples[0].Cmd(); cmd != `git commit --message "<message>"` {
+		t.Fatalf(`Cmd() = %s, expected git commit --message "<message>"`, cmd)
+	}
+
+	if _, err := Parse("readme", "common", "# Readme\n\nNot a page."); err == nil {
+		t.Fatalf("Parse of a file without examples succeeded, expected an error")
+	}
+}
+
+// test prompts find the example that answers them, also when misspelled
+func TestSearch(t *testing.T) {
+	corpus, err := Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	for prompt, expected := range map[string]string{
+		"extract a tar.gz archive":                "tar",
+		"find files larger than 100MB":            "find",
+		"undo the last git commit":                "git",
+		"compress a folder":                       "zip",
+		"count unique values in a csv column":     "awk",
+		"dwonload a file and resume if it breaks": "wget",
+	} {
+		matches := corpus.Search(prompt, 3)
+		if len(matches) == 0 || matches[0].Page != expected {
+			t.Fatalf(`Search("%s") = %v, expected a %s example first`, prompt, matches, expected)
+		}
+	}
+
+	if matches := corpus.Search("xyzzy", 3); len(matches) != 0 {
+		t.Fatalf(`Search("xyzzy") = %v, expected no matches`, matches)
+	}
+	if similar := corpus.Similar("dockr", 3); len(similar) == 0 || similar[0] != "docker" {
+		t.Fatalf(`Similar("dockr") = %v, expected docker`, similar)
+	}
+}
+
+// test pages of a tldr-pages checkout are imported without translations and replace bundled pages
+func TestImport(t *testing.T) {
+	checkout := t.TempDir()
+	for file, text := range map[string]string{
+		"pages/common/git-commit.md":    page,
+		"pages/common/tar.md":           "# tar\n\n> Imported.\n\n- Imported example:\n\n`tar --imported`\n",
+		"pages/" + Platform() + "/x.md": "# x\n\n> Platform page.\n\n- Example:\n\n`x`\n",
+		"pages.de/common/ls.md":         "# ls\n\n> Übersetzt.\n\n- Beispiel:\n\n`ls`\n",
+		"README.md":                     "# tldr\n",
+	} {
+		os.MkdirAll(filepath.Join(checkout, filepath.Dir(file)), 0700)
+		os.WriteFile(filepath.Join(checkout, file), []byte(text), 0600)
+	}
+
+	dir := t.TempDir()
+	n, err := Import(checkout, dir)
+	if n != 3 || err != nil {
+		t.Fatalf("Import = (%d, %v), expected 3 pages", n, err)
+	}
+
+	corpus, _ := Load(dir)
+	if p := corpus.Page("tar"); p == nil || p.Description != "Imported." {
+		t.Fatalf("Page(tar) = %v, expected the imported page", p)
+	}
+	if corpus.Page("git commit") == nil || corpus.Page("x") == nil {
+		t.Fatalf("imported pages are missing from the corpus")
+	}
+	if p := corpus.Page("ls"); p == nil || p.Description == "Übersetzt." {
+		t.Fatalf("Page(ls) = %v, expected the bundled page and not the translation", p)
+	}
+
+	if _, err := Import(t.TempDir(), dir); err == nil {
+		t.Fatalf("Import of an empty directory succeeded, expected an error")
+	}
+}
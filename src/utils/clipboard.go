@@ -1,20 +1,27 @@
 package utils
 
 import (
-	"fmt"
-	"os"
+	"sync"
 
 	"golang.design/x/clipboard"
 )
 
-func init() {
-	err := clipboard.Init()
-	if err != nil {
-		fmt.Println("Failed to initialize clipboard.")
-		os.Exit(128)
+var (
+	clipboardOnce sync.Once
+	clipboardErr  error
+)
+
+// copy text to the clipboard, the clipboard is initialized on first use and
+// is not available on headless machines (e.g., CI or SSH sessions)
+func Clip(text string) error {
+	clipboardOnce.Do(func() {
+		clipboardErr = clipboard.Init()
+	})
+
+	if clipboardErr != nil {
+		return clipboardErr
 	}
-}
 
-func Clip(text string) {
 	clipboard.Write(clipboard.FmtText, []byte(text))
+	return nil
 }
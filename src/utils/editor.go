@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// the user's editor from $VISUAL or $EDITOR, with a platform default
+func Editor() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.Fields(os.Getenv(env)); len(editor) > 0 {
+			return editor
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// open text in the user's editor and return what was saved, a file with the
+// given extension (e.g., ".sh") lets the editor pick the right syntax
+func EditInEditor(text string, ext string) (string, error) {
+	f, err := os.CreateTemp("", "fl-edit-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(text + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	editor := Editor()
+	cmd := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = PromptInput(), os.Stdout, os.Stderr
+	if err = cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s failed: %v", editor[0], err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -19,10 +20,10 @@ type GitHubProfile struct {
 	Email string      `json:"email"`
 }
 
-func GetGitHubAccessToken(clientID string) (token GitHubAccessToken, err error) {
+func GetGitHubAccessToken(ctx context.Context, clientID string) (token GitHubAccessToken, err error) {
 	githubDeviceLogin := fmt.Sprintf("https://github.com/login/device/code?client_id=%s&scope=user", clientID)
 
-	_, body, err := PostJSON(githubDeviceLogin, nil)
+	_, body, err := PostJSON(ctx, githubDeviceLogin, nil)
 	if err != nil {
 		return
 	}
@@ -42,10 +43,12 @@ func GetGitHubAccessToken(clientID string) (token GitHubAccessToken, err error)
 	deviceCode := response.DeviceCode
 	interval := response.Interval
 
-	Clip(response.UserCode)
-
 	fmt.Println("Press 'return' key to open your browser automatically and login to Github.")
-	fmt.Println("You will paste the following GitHub device code to login (already copied to your clipboard):", response.UserCode)
+	if Clip(response.UserCode) == nil {
+		fmt.Println("You will paste the following GitHub device code to login (already copied to your clipboard):", response.UserCode)
+	} else {
+		fmt.Println("You will enter the following GitHub device code to login:", response.UserCode)
+	}
 	// Wait until any key is pressed to continue so the user has time to read the message
 	fmt.Scanln()
 
@@ -60,7 +63,7 @@ func GetGitHubAccessToken(clientID string) (token GitHubAccessToken, err error)
 		fmt.Print(".")
 		// Make a POST request to the GitHub API to exchange the device code for an access token
 		accessTokenURL := "https://github.com/login/oauth/access_token?client_id=" + clientID + "&device_code=" + deviceCode + "&grant_type=urn:ietf:params:oauth:grant-type:device_code"
-		_, body, err = PostJSON(accessTokenURL, nil)
+		_, body, err = PostJSON(ctx, accessTokenURL, nil)
 		if err != nil {
 			fmt.Println()
 			return
@@ -80,7 +83,13 @@ func GetGitHubAccessToken(clientID string) (token GitHubAccessToken, err error)
 		}
 
 		// Polling interval
-		time.Sleep(time.Duration(interval) * time.Second)
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			err = ctx.Err()
+			return
+		case <-time.After(time.Duration(interval) * time.Second):
+		}
 	}
 
 	if token.AccessToken == "" {
@@ -91,13 +100,13 @@ func GetGitHubAccessToken(clientID string) (token GitHubAccessToken, err error)
 	return
 }
 
-func ExchangeTokenForGitHubUserProfile(token string) (profile string, err error) {
-	_, profile, err = GetJSON("https://api.github.com/user", nil, token)
+func ExchangeTokenForGitHubUserProfile(ctx context.Context, token string) (profile string, err error) {
+	_, profile, err = GetJSON(ctx, "https://api.github.com/user", nil, token)
 	return
 }
 
-func GetGitHubUserProfile(clientID string) (profile GitHubProfile, err error) {
-	token, err := GetGitHubAccessToken(clientID)
+func GetGitHubUserProfile(ctx context.Context, clientID string) (profile GitHubProfile, err error) {
+	token, err := GetGitHubAccessToken(ctx, clientID)
 	if err != nil {
 		return
 	}
@@ -107,7 +116,7 @@ func GetGitHubUserProfile(clientID string) (profile GitHubProfile, err error) {
 		return
 	}
 
-	response, err := ExchangeTokenForGitHubUserProfile(token.AccessToken)
+	response, err := ExchangeTokenForGitHubUserProfile(ctx, token.AccessToken)
 	if err != nil {
 		return
 	}
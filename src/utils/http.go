@@ -0,0 +1,259 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultHTTPTimeout = 60 * time.Second
+	DefaultHTTPRetries = 3
+
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+	maxRetryAfter  = 60 * time.Second // a longer Retry-After fails instead of waiting
+)
+
+// settings of the shared HTTP client, from the http.* config keys
+type HTTPConfig struct {
+	Timeout  time.Duration // limit of each attempt, zero uses DefaultHTTPTimeout
+	Retries  int           // retries of a failed request, zero uses DefaultHTTPRetries and negative disables them
+	Proxy    string        // proxy URL for all requests, empty uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	CAFile   string        // PEM bundle trusted in addition to the system roots
+	CertFile string        // client certificate for mutual TLS
+	KeyFile  string        // key of the client certificate
+}
+
+// one client for all requests so connections are reused; its transport is
+// nil, i.e. http.DefaultTransport, unless the config needs its own
+var (
+	httpClient  = &http.Client{Timeout: DefaultHTTPTimeout}
+	httpRetries = DefaultHTTPRetries
+)
+
+func ConfigureHTTP(conf HTTPConfig) error {
+	timeout := conf.Timeout
+	if timeout == 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	retries := conf.Retries
+	if retries == 0 {
+		retries = DefaultHTTPRetries
+	} else if retries < 0 {
+		retries = 0
+	}
+
+	transport, err := newTransport(conf)
+	if err != nil {
+		return err
+	}
+
+	httpClient = &http.Client{Timeout: timeout}
+	if transport != nil {
+		httpClient.Transport = transport
+	}
+	httpRetries = retries
+	return nil
+}
+
+// a transport with the proxy, CA bundle and client certificate, nil if none is set
+func newTransport(conf HTTPConfig) (*http.Transport, error) {
+	if conf.Proxy == "" && conf.CAFile == "" && conf.CertFile == "" && conf.KeyFile == "" {
+		return nil, nil
+	}
+
+	var transport *http.Transport
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = t.Clone()
+	} else {
+		transport = &http.Transport{Proxy: http.ProxyFromEnvironment, ForceAttemptHTTP2: true, MaxIdleConns: 100, IdleConnTimeout: 90 * time.Second}
+	}
+
+	if conf.Proxy != "" {
+		proxy, err := url.Parse(conf.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL '%s'", conf.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if conf.CAFile != "" {
+		pem, err := os.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading the CA bundle: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates in the CA bundle %s", conf.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if conf.CertFile != "" || conf.KeyFile != "" {
+		if conf.CertFile == "" || conf.KeyFile == "" {
+			return nil, fmt.Errorf("a client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(conf.CertFile, conf.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading the client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// the shared client without retries, e.g. for reachability checks
+func HTTPClient() *http.Client {
+	return httpClient
+}
+
+type idempotentKey struct{}
+
+// mark requests made with the context as safe to repeat, e.g. a POST that only reads
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	marked, _ := req.Context().Value(idempotentKey{}).(bool)
+	return marked
+}
+
+// send a request with the shared client; requests the server did not
+// process (no connection, 429) are retried, other failures only for
+// idempotent requests, with jittered exponential backoff or the server's
+// Retry-After
+func Do(req *http.Request) (*http.Response, error) {
+	idempotent := isIdempotent(req)
+	for attempt := 0; ; attempt++ {
+		resp, err := httpClient.Do(req)
+		if attempt >= httpRetries {
+			return resp, err
+		}
+
+		delay, retry := retryDelay(req, idempotent, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// whether to retry and after how long
+func retryDelay(req *http.Request, idempotent bool, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		if req.Context().Err() != nil {
+			return 0, false
+		}
+		if !idempotent && !notSent(err) {
+			return 0, false
+		}
+		return backoff(attempt), true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if !idempotent {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+		return after, after <= maxRetryAfter
+	}
+	return backoff(attempt), true
+}
+
+// errors that mean the request never reached the server
+func notSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// whether a request failed because the server could not be reached, e.g.
+// no network, DNS failures or timeouts, rather than an error reply
+func Unreachable(err error) bool {
+	var urlErr *url.Error
+	if errors.Is(err, context.Canceled) || !errors.As(err, &urlErr) {
+		return false
+	}
+	var netErr net.Error
+	return notSent(err) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// exponential backoff with full jitter in the upper half
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// a Retry-After header in seconds or as an HTTP date
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		delay := time.Until(at)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// a server that fails with status until it has been called fails times
+func failingServer(t *testing.T, status int, fails int32, retryAfter string) (*httptest.Server, *int32) {
+	calls := new(int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) <= fails {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+// test requests are retried only when that is safe
+func TestRetries(t *testing.T) {
+	ConfigureHTTP(HTTPConfig{Retries: 3})
+	ctx := context.Background()
+
+	server, calls := failingServer(t, http.StatusServiceUnavailable, 2, "0")
+	if status, _, err := GetJSON(ctx, server.URL, nil, ""); status != 200 || err != nil || *calls != 3 {
+		t.Fatalf("GET after two 503s = (%d, %v) in %d calls, expected 200 in 3 calls", status, err, *calls)
+	}
+
+	server, calls = failingServer(t, http.StatusServiceUnavailable, 1, "0")
+	if status, _, _ := PostJSON(ctx, server.URL, nil); status != 503 || *calls != 1 {
+		t.Fatalf("POST after a 503 = %d in %d calls, expected 503 without a retry", status, *calls)
+	}
+
+	server, calls = failingServer(t, http.StatusServiceUnavailable, 1, "0")
+	if status, _, _ := PostJSON(Idempotent(ctx), server.URL, nil); status != 200 || *calls != 2 {
+		t.Fatalf("idempotent POST after a 503 = %d in %d calls, expected 200 in 2 calls", status, *calls)
+	}
+
+	server, calls = failingServer(t, http.StatusTooManyRequests, 1, "0")
+	if status, _, _ := PostJSON(ctx, server.URL, nil); status != 200 || *calls != 2 {
+		t.Fatalf("POST after a 429 = %d in %d calls, expected 200 in 2 calls", status, *calls)
+	}
+
+	server, calls = failingServer(t, http.StatusTooManyRequests, 1, "3600")
+	if status, _, _ := PostJSON(ctx, server.URL, nil); status != 429 || *calls != 1 {
+		t.Fatalf("POST after a 429 with Retry-After 3600 = %d in %d calls, expected 429 without waiting", status, *calls)
+	}
+
+	ConfigureHTTP(HTTPConfig{Retries: -1})
+	server, calls = failingServer(t, http.StatusServiceUnavailable, 1, "0")
+	if status, _, _ := GetJSON(ctx, server.URL, nil, ""); status != 503 || *calls != 1 {
+		t.Fatalf("GET with retries disabled = %d in %d calls, expected 503 in 1 call", status, *calls)
+	}
+	ConfigureHTTP(HTTPConfig{})
+}
+
+// test a server whose certificate is signed by a custom CA is trusted with the CA bundle
+func TestCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	defer ConfigureHTTP(HTTPConfig{})
+
+	ConfigureHTTP(HTTPConfig{Retries: -1})
+	if _, _, err := GetJSON(context.Background(), server.URL, nil, ""); err == nil {
+		t.Fatalf("GET from a server with an unknown CA succeeded, expected a certificate error")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+	if err := ConfigureHTTP(HTTPConfig{CAFile: bundle}); err != nil {
+		t.Fatalf("ConfigureHTTP with a CA bundle failed: %v", err)
+	}
+	if status, _, err := GetJSON(context.Background(), server.URL, nil, ""); status != 200 || err != nil {
+		t.Fatalf("GET with the CA bundle = (%d, %v), expected 200", status, err)
+	}
+
+	if err := ConfigureHTTP(HTTPConfig{CertFile: bundle}); err == nil {
+		t.Fatalf("ConfigureHTTP with a certificate but no key succeeded, expected an error")
+	}
+}
+
+// test only failures to reach the server count as unreachable
+func TestUnreachable(t *testing.T) {
+	ConfigureHTTP(HTTPConfig{Retries: -1})
+	defer ConfigureHTTP(HTTPConfig{})
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+	if _, _, err := GetJSON(context.Background(), url, nil, ""); !Unreachable(err) {
+		t.Fatalf("Unreachable(%v) = false for a closed server, expected true", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := GetJSON(ctx, url, nil, ""); Unreachable(err) {
+		t.Fatalf("Unreachable(%v) = true for a canceled request, expected false", err)
+	}
+}
@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// returned when the user leaves the line editor with Ctrl-C or Esc
+var ErrEditCanceled = errors.New("edit canceled")
+
+// key codes read in raw mode
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlK     = 11
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEnter     = 13
+	keyEscape    = 27
+	keyBackspace = 127
+	keyCtrlH     = 8
+)
+
+// a single line being edited, the cursor is an index into line
+type lineEditor struct {
+	out     *os.File
+	prompt  []rune
+	line    []rune
+	pos     int
+	row     int // terminal row of the cursor relative to the start of the prompt
+	history []string
+	recall  int    // index into history while browsing it, len(history) for the edited line
+	draft   []rune // the edited line while browsing the history
+}
+
+// edit a line in place with readline-style keys: arrows, Home/End, Ctrl-A/E,
+// Ctrl-K/U/W and Up/Down to recall earlier lines from history (oldest first)
+func EditLine(prompt string, initial string, history []string) (string, error) {
+	in := PromptInput()
+	if !IsTerminal(in) || !IsTerminal(os.Stdout) {
+		return "", fmt.Errorf("line editing needs a terminal")
+	}
+
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(int(in.Fd()), state)
+
+	e := &lineEditor{
+		out:     os.Stdout,
+		prompt:  []rune(prompt),
+		line:    []rune(initial),
+		pos:     len([]rune(initial)),
+		history: history,
+		recall:  len(history),
+	}
+	e.redraw()
+
+	reader := bufio.NewReader(in)
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case keyEnter, '\n':
+			e.end()
+			return string(e.line), nil
+		case keyCtrlC:
+			e.end()
+			return "", ErrEditCanceled
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				e.end()
+				return "", ErrEditCanceled
+			}
+			e.delete(e.pos)
+		case keyEscape:
+			if !e.escape(reader) {
+				e.end()
+				return "", ErrEditCanceled
+			}
+		case keyCtrlA:
+			e.pos = 0
+		case keyCtrlE:
+			e.pos = len(e.line)
+		case keyCtrlB:
+			e.left()
+		case keyCtrlF:
+			e.right()
+		case keyCtrlP:
+			e.recallHistory(-1)
+		case keyCtrlN:
+			e.recallHistory(1)
+		case keyBackspace, keyCtrlH:
+			if e.pos > 0 {
+				e.pos--
+				e.delete(e.pos)
+			}
+		case keyCtrlK:
+			e.line = e.line[:e.pos]
+		case keyCtrlU:
+			e.line = e.line[e.pos:]
+			e.pos = 0
+		case keyCtrlW:
+			start := e.pos
+			for start > 0 && unicode.IsSpace(e.line[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(e.line[start-1]) {
+				start--
+			}
+			e.line = append(e.line[:start], e.line[e.pos:]...)
+			e.pos = start
+		default:
+			if unicode.IsPrint(r) {
+				e.line = append(e.line[:e.pos], append([]rune{r}, e.line[e.pos:]...)...)
+				e.pos++
+			}
+		}
+		e.redraw()
+	}
+}
+
+// handle an escape sequence, returns false for a lone Esc
+func (e *lineEditor) escape(reader *bufio.Reader) bool {
+	if reader.Buffered() == 0 {
+		return false
+	}
+
+	next, _, _ := reader.ReadRune()
+	if next != '[' && next != 'O' {
+		return true
+	}
+
+	// read the parameters up to the final byte, e.g. "3~" for Delete
+	seq := ""
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return true
+		}
+		seq += string(r)
+		if r >= 0x40 && r <= 0x7e {
+			break
+		}
+	}
+
+	switch seq {
+	case "D":
+		e.left()
+	case "C":
+		e.right()
+	case "A":
+		e.recallHistory(-1)
+	case "B":
+		e.recallHistory(1)
+	case "H", "1~", "7~":
+		e.pos = 0
+	case "F", "4~", "8~":
+		e.pos = len(e.line)
+	case "3~":
+		e.delete(e.pos)
+	case "1;5D", "1;3D":
+		e.wordLeft()
+	case "1;5C", "1;3C":
+		e.wordRight()
+	}
+	return true
+}
+
+func (e *lineEditor) left() {
+	if e.pos > 0 {
+		e.pos--
+	}
+}
+
+func (e *lineEditor) right() {
+	if e.pos < len(e.line) {
+		e.pos++
+	}
+}
+
+func (e *lineEditor) wordLeft() {
+	for e.pos > 0 && unicode.IsSpace(e.line[e.pos-1]) {
+		e.pos--
+	}
+	for e.pos > 0 && !unicode.IsSpace(e.line[e.pos-1]) {
+		e.pos--
+	}
+}
+
+func (e *lineEditor) wordRight() {
+	for e.pos < len(e.line) && unicode.IsSpace(e.line[e.pos]) {
+		e.pos++
+	}
+	for e.pos < len(e.line) && !unicode.IsSpace(e.line[e.pos]) {
+		e.pos++
+	}
+}
+
+func (e *lineEditor) delete(i int) {
+	if i < len(e.line) {
+		e.line = append(e.line[:i], e.line[i+1:]...)
+	}
+}
+
+// move through the history, keeping the edited line to come back to
+func (e *lineEditor) recallHistory(step int) {
+	next := e.recall + step
+	if next < 0 || next > len(e.history) {
+		return
+	}
+
+	if e.recall == len(e.history) {
+		e.draft = append([]rune{}, e.line...)
+	}
+	e.recall = next
+
+	if next == len(e.history) {
+		e.line = append([]rune{}, e.draft...)
+	} else {
+		e.line = []rune(e.history[next])
+	}
+	e.pos = len(e.line)
+}
+
+// rewrite the prompt and line, which may wrap over several terminal rows
+func (e *lineEditor) redraw() {
+	width, _, err := term.GetSize(int(e.out.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+
+	var sb strings.Builder
+	if e.row > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dA", e.row)
+	}
+	sb.WriteString("\r\x1b[J")
+	sb.WriteString(string(e.prompt))
+	sb.WriteString(string(e.line))
+
+	// terminals wait at the last column instead of wrapping, move on explicitly
+	total := len(e.prompt) + len(e.line)
+	if total > 0 && total%width == 0 {
+		sb.WriteString("\r\n")
+	}
+
+	endRow := total / width
+	cursor := len(e.prompt) + e.pos
+	row, col := cursor/width, cursor%width
+	if endRow > row {
+		fmt.Fprintf(&sb, "\x1b[%dA", endRow-row)
+	}
+	sb.WriteString("\r")
+	if col > 0 {
+		fmt.Fprintf(&sb, "\x1b[%dC", col)
+	}
+
+	e.row = row
+	e.out.WriteString(sb.String())
+}
+
+// leave the cursor on a new line below the edited text
+func (e *lineEditor) end() {
+	e.pos = len(e.line)
+	e.redraw()
+	e.out.WriteString("\r\n")
+}
@@ -1,20 +1,27 @@
 package utils
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"runtime"
 	"strings"
+	"sync"
+
+	"golang.org/x/term"
 )
 
 const (
 	myIpAPI = "https://api.ipify.org"
 )
 
-func GetJSON(urlStr string, queryParams map[string]string, bearer string) (int, string, error) {
+func GetJSON(ctx context.Context, urlStr string, queryParams map[string]string, bearer string) (int, string, error) {
 	// Step 1: Parse the URL
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
@@ -31,7 +38,7 @@ func GetJSON(urlStr string, queryParams map[string]string, bearer string) (int,
 	}
 
 	// Step 3: Create a new HTTP GET request
-	req, err := http.NewRequest("GET", parsedURL.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", parsedURL.String(), nil)
 	if err != nil {
 		return 0, "", fmt.Errorf("error creating request: %w", err)
 	}
@@ -40,9 +47,8 @@ func GetJSON(urlStr string, queryParams map[string]string, bearer string) (int,
 		req.Header.Set("Authorization", "Bearer "+bearer)
 	}
 
-	// Step 4: Perform the HTTP request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// Step 4: Perform the HTTP request with the shared client
+	resp, err := Do(req)
 	if err != nil {
 		return 0, "", fmt.Errorf("error sending request: %w", err)
 	}
@@ -58,7 +64,12 @@ func GetJSON(urlStr string, queryParams map[string]string, bearer string) (int,
 	return resp.StatusCode, string(body), nil
 }
 
-func PostJSON(url string, payload interface{}) (int, []byte, error) {
+func PostJSON(ctx context.Context, url string, payload interface{}) (int, []byte, error) {
+	return PostJSONWithHeaders(ctx, url, payload, nil)
+}
+
+// post JSON with additional request headers (e.g., Authorization)
+func PostJSONWithHeaders(ctx context.Context, url string, payload interface{}, headers map[string]string) (int, []byte, error) {
 	// Step 1: Marshal the payload to JSON
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
@@ -66,7 +77,7 @@ func PostJSON(url string, payload interface{}) (int, []byte, error) {
 	}
 
 	// Step 2: Create a new HTTP POST request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return 0, nil, fmt.Errorf("error creating request: %w", err)
 	}
@@ -74,10 +85,12 @@ func PostJSON(url string, payload interface{}) (int, []byte, error) {
 	// Step 3: Set the Accept and Content-Type header to application/json
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
-	// Step 4: Perform the HTTP request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// Step 4: Perform the HTTP request with the shared client
+	resp, err := Do(req)
 	if err != nil {
 		return 0, nil, fmt.Errorf("error sending request: %w", err)
 	}
@@ -97,8 +110,12 @@ func PostJSON(url string, payload interface{}) (int, []byte, error) {
  * Get external IP address
  * @return IP as string, error
  */
-func GetExternalIP() (string, error) {
-	resp, err := http.Get(myIpAPI)
+func GetExternalIP(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", myIpAPI, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %w", err)
+	}
+	resp, err := Do(req)
 	if err != nil {
 		return "", fmt.Errorf("error determining IP address: %w", err)
 	}
@@ -123,12 +140,100 @@ func Log(verbose bool, msg string, rest ...interface{}) {
 	}
 }
 
+// whether f is an interactive terminal rather than a pipe or a file
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// whether f is a pipe or a redirected file, a terminal or /dev/null is not
+func IsPiped(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	return mode&os.ModeNamedPipe != 0 || mode.IsRegular()
+}
+
+var (
+	ttyOnce sync.Once
+	tty     *os.File
+)
+
+// where answers to prompts are read from: stdin, or the terminal when stdin
+// is data piped to fl (e.g. cat error.log | fl -p ...)
+func PromptInput() *os.File {
+	if !IsPiped(os.Stdin) {
+		return os.Stdin
+	}
+
+	ttyOnce.Do(func() {
+		name := "/dev/tty"
+		if runtime.GOOS == "windows" {
+			name = "CONIN$"
+		}
+		if f, err := os.OpenFile(name, os.O_RDWR, 0); err == nil {
+			tty = f
+		}
+	})
+	if tty != nil {
+		return tty
+	}
+	return os.Stdin
+}
+
 func PromptYesNo(prompt string) bool {
 	var userInput string
 
 	fmt.Printf("%s (y/n): ", prompt)
-	fmt.Scanln(&userInput)
+	fmt.Fscanln(PromptInput(), &userInput)
 
 	userInput = strings.ToLower(strings.TrimSpace(userInput))
 	return userInput == "y" || userInput == "yes"
 }
+
+// ask the user to pick one of the choices, e.g. "y/n/e", returns the choice or
+// an empty string if the answer was not one of them
+func PromptChoice(prompt string, choices string) string {
+	var userInput string
+
+	fmt.Printf("%s (%s): ", prompt, choices)
+	fmt.Fscanln(PromptInput(), &userInput)
+
+	userInput = strings.ToLower(strings.TrimSpace(userInput))
+	switch userInput {
+	case "yes":
+		userInput = "y"
+	case "no":
+		userInput = "n"
+	}
+
+	for _, c := range strings.Split(choices, "/") {
+		if userInput == c {
+			return c
+		}
+	}
+	return ""
+}
+
+// ask for a secret on the terminal without echoing it
+func PromptPassword(prompt string) (string, error) {
+	in := PromptInput()
+	if !IsTerminal(in) {
+		return "", fmt.Errorf("no terminal to ask for it")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(int(in.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(password), err
+}
+
+// ask the user to type a phrase to confirm a risky action
+func PromptConfirm(prompt string, phrase string) bool {
+	fmt.Printf("%s Type '%s' to continue: ", prompt, phrase)
+
+	reader := bufio.NewReader(PromptInput())
+	userInput, _ := reader.ReadString('\n')
+	return strings.TrimSpace(userInput) == phrase
+}